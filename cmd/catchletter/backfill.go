@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/mailwatcher"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/notifier"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/outbox"
+)
+
+// dateLayout - формат дат в флагах -since и -before
+const dateLayout = "2006-01-02"
+
+// ruleSummary - итог backfill по одному правилу
+type ruleSummary struct {
+	Rule     string `json:"rule"`
+	Matched  int    `json:"matched"`
+	NearMiss int    `json:"near_miss"`
+}
+
+// backfill повторно прогоняет правила по письмам из ящика за период или диапазон UID.
+// Состояние монитора (последний UID) не меняется, уведомления отправляются только с -notify
+func backfill(args []string) int {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	configPath := fs.String("config", "", "путь к конфигу (по умолчанию ищется в стандартных местах)")
+	since := fs.String("since", "", "письма с этой даты включительно, "+dateLayout)
+	before := fs.String("before", "", "письма до этой даты, не включая её, "+dateLayout)
+	uidRange := fs.String("uid", "", "диапазон UID from:to, to можно опустить или указать *")
+	rulesPath := fs.String("rules", "", "файл с правилами вместо правил из конфига")
+	notify := fs.Bool("notify", false, "отправить уведомления по сработавшим правилам")
+	format := fs.String("format", "table", "формат вывода: table или json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := checkFormat(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	query, err := parseSearchQuery(*since, *before, *uidRange)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ошибка загрузки конфига: %v\n", err)
+		return 2
+	}
+
+	rules := cfg.Rules
+	if *rulesPath != "" {
+		rules, err = config.LoadRules(*rulesPath, &cfg.Scoring)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	engine := filter.NewConfiguredEngine(cfg, rules)
+
+	client := mailwatcher.NewIMAPClient(cfg)
+	if err := client.Connect(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer client.Close()
+
+	emails, err := client.Search(query)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var send func(alerts []*models.Alert)
+	if *notify {
+		manager, err := notifier.NewManager(cfg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+
+		// Очереди у backfill нет: отложенное тихими часами и лимитом уходит
+		// сводками в конце, без учёта лимитов
+		defer manager.Close()
+		deferred := &deferredAlerts{}
+		defer deferred.flush(manager)
+
+		send = func(alerts []*models.Alert) { notifyAlerts(manager, deferred, alerts) }
+	}
+
+	results := make([]emailResult, 0, len(emails))
+	for _, email := range emails {
+		result, alerts := evaluateEmail(engine, email.Date.Format("2006-01-02 15:04"), email)
+		results = append(results, result)
+		if send != nil && len(alerts) > 0 {
+			send(alerts)
+		}
+	}
+
+	if err := writeBackfill(*format, results); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// parseSearchQuery собирает критерии поиска из флагов
+func parseSearchQuery(since, before, uidRange string) (mailwatcher.SearchQuery, error) {
+	var query mailwatcher.SearchQuery
+	var err error
+
+	if since != "" {
+		if query.Since, err = time.ParseInLocation(dateLayout, since, time.Local); err != nil {
+			return query, fmt.Errorf("неверная дата -since: %s", since)
+		}
+	}
+	if before != "" {
+		if query.Before, err = time.ParseInLocation(dateLayout, before, time.Local); err != nil {
+			return query, fmt.Errorf("неверная дата -before: %s", before)
+		}
+	}
+	if !query.Since.IsZero() && !query.Before.IsZero() && !query.Before.After(query.Since) {
+		return query, fmt.Errorf("-before должна быть позже -since")
+	}
+
+	if uidRange != "" {
+		from, to, _ := strings.Cut(uidRange, ":")
+		uidFrom, err := strconv.ParseUint(from, 10, 32)
+		if err != nil || uidFrom == 0 {
+			return query, fmt.Errorf("неверный диапазон -uid: %s", uidRange)
+		}
+		query.UIDFrom = uint32(uidFrom)
+
+		if to != "" && to != "*" {
+			uidTo, err := strconv.ParseUint(to, 10, 32)
+			if err != nil || uidTo < uidFrom {
+				return query, fmt.Errorf("неверный диапазон -uid: %s", uidRange)
+			}
+			query.UIDTo = uint32(uidTo)
+		}
+	}
+
+	if query.Since.IsZero() && query.Before.IsZero() && query.UIDFrom == 0 {
+		return query, fmt.Errorf("укажите -since, -before или -uid")
+	}
+	return query, nil
+}
+
+// notifyAlerts отправляет алерты письма, по одному на действие, как это делает монитор
+func notifyAlerts(manager *notifier.Manager, deferred *deferredAlerts, alerts []*models.Alert) {
+	sent := make(map[models.Action]bool)
+	for _, alert := range alerts {
+		for _, action := range alert.Rule.Actions {
+			if sent[action] || !manager.HasNotifier(action.Type) {
+				continue
+			}
+			sent[action] = true
+
+			var deferral *outbox.Deferral
+			if err := manager.Send(action, alert); errors.As(err, &deferral) {
+				deferred.add(action, deferral.Group, alert)
+			} else if err != nil {
+				fmt.Fprintf(os.Stderr, "ошибка отправки %s: %v\n", action, err)
+			}
+		}
+	}
+}
+
+// deferredAlerts - отложенные при backfill алерты по действиям и группам в порядке поступления
+type deferredAlerts struct {
+	keys   []deferredKey
+	alerts map[deferredKey][]*models.Alert
+}
+
+type deferredKey struct {
+	action models.Action
+	group  string
+}
+
+func (d *deferredAlerts) add(action models.Action, group string, alert *models.Alert) {
+	key := deferredKey{action, group}
+	if d.alerts == nil {
+		d.alerts = make(map[deferredKey][]*models.Alert)
+	}
+	if _, ok := d.alerts[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.alerts[key] = append(d.alerts[key], alert)
+}
+
+// flush отправляет отложенное: по сводке на действие и группу
+func (d *deferredAlerts) flush(manager *notifier.Manager) {
+	for _, key := range d.keys {
+		alert := notifier.MergeDeferred(key.group, d.alerts[key])
+		if err := manager.Deliver(key.action, alert); err != nil {
+			fmt.Fprintf(os.Stderr, "ошибка отправки %s: %v\n", key.action, err)
+		}
+	}
+}
+
+// summarize считает срабатывания по правилам, правила сортируются по числу срабатываний
+func summarize(results []emailResult) []ruleSummary {
+	byRule := make(map[string]*ruleSummary)
+	for _, result := range results {
+		for _, match := range result.Matches {
+			summary, ok := byRule[match.Rule]
+			if !ok {
+				summary = &ruleSummary{Rule: match.Rule}
+				byRule[match.Rule] = summary
+			}
+			if match.NearMiss {
+				summary.NearMiss++
+			} else {
+				summary.Matched++
+			}
+		}
+	}
+
+	summaries := make([]ruleSummary, 0, len(byRule))
+	for _, summary := range byRule {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Matched != summaries[j].Matched {
+			return summaries[i].Matched > summaries[j].Matched
+		}
+		return summaries[i].Rule < summaries[j].Rule
+	})
+	return summaries
+}
+
+// writeBackfill выводит результаты по письмам и итог по правилам
+func writeBackfill(format string, results []emailResult) error {
+	summaries := summarize(results)
+
+	if format == "json" {
+		return writeJSON(struct {
+			Emails  []emailResult `json:"emails"`
+			Summary []ruleSummary `json:"summary"`
+		}{results, summaries})
+	}
+
+	if err := writeResults(format, results); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nПисем: %d\n", len(results))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ПРАВИЛО\tСРАБОТАЛО\tПОЧТИ")
+	for _, summary := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", summary.Rule, summary.Matched, summary.NearMiss)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/ruletest"
+)
+
+// defaultSuitePath - тесты правил по умолчанию, рядом с конфигом
+const defaultSuitePath = "configs/rules_test.yaml"
+
+// rulesCheck запускает регрессионные тесты правил
+func rulesCheck(args []string) int {
+	fs := flag.NewFlagSet("rules check", flag.ContinueOnError)
+	configPath := fs.String("config", "", "путь к конфигу (по умолчанию ищется в стандартных местах)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	engine, err := loadEngine(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{defaultSuitePath}
+	}
+
+	var results []ruletest.Result
+	for _, path := range paths {
+		suite, err := ruletest.Load(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+
+		for _, result := range suite.Run(engine) {
+			switch {
+			case result.Err != nil:
+				fmt.Printf("ERROR %s: %s: %v\n", path, result.Case, result.Err)
+			case result.Passed():
+				fmt.Printf("ok    %s: %s\n", path, result.Case)
+			default:
+				fmt.Printf("FAIL  %s: %s\n", path, result.Case)
+				for _, diff := range result.Diffs {
+					fmt.Printf("      %s\n", diff)
+				}
+			}
+			results = append(results, result)
+		}
+	}
+
+	fmt.Println(ruletest.Summary(results))
+	for _, result := range results {
+		if result.Err != nil {
+			return 2
+		}
+	}
+	for _, result := range results {
+		if !result.Passed() {
+			return 1
+		}
+	}
+	return 0
+}
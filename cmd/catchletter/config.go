@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/notifier"
+)
+
+// configEnv печатает переменные окружения, которыми можно задать настройки
+func configEnv(args []string) int {
+	fs := flag.NewFlagSet("config env", flag.ContinueOnError)
+	format := fs.String("format", "table", "формат вывода: table или json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := checkFormat(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	vars := config.EnvVars()
+	if *format == "json" {
+		if err := writeJSON(vars); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ПЕРЕМЕННАЯ\tНАСТРОЙКА\tТИП")
+	for _, v := range vars {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Name, v.Path, v.Type)
+	}
+	if err := tw.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Println("\nДля строковых настроек есть вариант <ПЕРЕМЕННАЯ>_FILE: значение читается из файла.")
+	return 0
+}
+
+// configValidate проверяет конфиг и печатает все найденные проблемы с местом в файле.
+// Код выхода: 0 - конфиг корректен, 1 - есть проблемы, 2 - конфиг не удалось прочитать
+func configValidate(args []string) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	configPath := fs.String("config", "", "путь к конфигу (по умолчанию ищется в стандартных местах)")
+	format := fs.String("format", "table", "формат вывода: table или json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := checkFormat(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	issues := []config.Issue{}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		var verr *config.ValidationError
+		if !errors.As(err, &verr) {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		issues = verr.Issues
+	} else {
+		// Шаблоны разбираются только при создании нотификаторов, проверяем их здесь же,
+		// чтобы конфиг, прошедший проверку, не упал при запуске
+		issues = append(issues, notifier.TemplateIssues(cfg)...)
+	}
+
+	if *format == "json" {
+		if err := writeJSON(issues); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	} else if len(issues) == 0 {
+		fmt.Println("Конфиг корректен")
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "МЕСТО\tНАСТРОЙКА\tПРОБЛЕМА")
+		for _, issue := range issues {
+			place := "-"
+			if issue.File != "" {
+				place = fmt.Sprintf("%s:%d:%d", issue.File, issue.Line, issue.Column)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", place, issue.Path, issue.Message)
+		}
+		if err := tw.Flush(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	if len(issues) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// configSchema печатает JSON Schema конфига для подсказок и проверки в редакторе
+func configSchema(args []string) int {
+	fs := flag.NewFlagSet("config schema", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	schema, err := config.Schema()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if _, err := os.Stdout.Write(schema); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
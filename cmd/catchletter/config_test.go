@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigValidateExitCode(t *testing.T) {
+	dir := t.TempDir()
+	ruleTemplate := strings.Replace(rulesConfig, `    actions: ["telegram"]`, `    actions: ["telegram"]
+    templates:
+      telegram: "{{.Rule.Name"`, 1)
+	notifierTemplate := rulesConfig + `notifiers:
+  ntfy:
+    enabled: true
+    server_url: "https://ntfy.example.com"
+    topic: "mail"
+    template: "{{if .Rule}}"
+`
+
+	tests := []struct {
+		name     string
+		config   string
+		expected int
+	}{
+		{"корректный конфиг", rulesConfig, 0},
+		{"сломанный шаблон правила", ruleTemplate, 1},
+		{"сломанный шаблон нотификатора", notifierTemplate, 1},
+		{"ошибка проверки конфига", strings.Replace(rulesConfig, "min_score: 50", "min_score: -1", 1), 1},
+		{"конфиг не читается", "rules: [", 2},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("config%d.yaml", i))
+			if err := os.WriteFile(path, []byte(tt.config), 0600); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := configValidate([]string{"-config", path}); got != tt.expected {
+				t.Errorf("incorrect exit code, expected: %d, got: %d", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/mailwatcher"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// inputEmail - письмо и откуда оно прочитано
+type inputEmail struct {
+	Source string
+	Email  *models.Email
+}
+
+// readEmails читает письма из .eml и mbox файлов. "-" или пустой список - stdin
+func readEmails(paths []string) ([]inputEmail, error) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	var emails []inputEmail
+	for _, path := range paths {
+		var data []byte
+		var err error
+		if path == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения %s: %w", path, err)
+		}
+
+		if !mailwatcher.IsMbox(data) {
+			email, err := mailwatcher.ParseEML(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			emails = append(emails, inputEmail{Source: path, Email: email})
+			continue
+		}
+
+		mbox, err := mailwatcher.ParseMbox(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for i, email := range mbox {
+			emails = append(emails, inputEmail{Source: fmt.Sprintf("%s#%d", path, i+1), Email: email})
+		}
+	}
+	return emails, nil
+}
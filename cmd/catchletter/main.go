@@ -0,0 +1,65 @@
+// catchletter - утилиты для работы с правилами и конфигурацией
+//
+//	catchletter rules explain [-config path] [-subject S] [-from F] [-body B] [-header "Name: value"] [file.eml]
+//	catchletter rules test [-config path] [-format table|json] [file.eml|file.mbox|-]...
+//	catchletter rules check [-config path] [suite.yaml]...
+//	catchletter backfill [-config path] [-since YYYY-MM-DD] [-before YYYY-MM-DD] [-uid from:to] [-rules path] [-notify] [-format table|json]
+//	catchletter config env [-format table|json]
+//	catchletter config validate [-config path] [-format table|json]
+//	catchletter config schema
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Использование: catchletter <команда> [флаги]
+
+Команды:
+  rules explain   показать, как каждое правило оценивает письмо
+  rules test      прогнать правила по .eml/mbox файлам или stdin
+  rules check     запустить регрессионные тесты правил из YAML
+  backfill        прогнать правила по письмам из ящика за период (-since/-before) или диапазон UID
+  config env      показать переменные окружения CATCHLETTER_* для всех настроек
+  config validate проверить конфиг и показать все проблемы со строкой и столбцом
+  config schema   напечатать JSON Schema конфига для редактора
+
+Коды выхода rules test: 0 - каждое письмо сработало хотя бы по одному правилу,
+1 - есть письма без срабатываний, 2 - ошибка конфига или писем
+Коды выхода rules check: 0 - все тесты прошли, 1 - есть расхождения, 2 - ошибка
+Коды выхода config validate: 0 - конфиг корректен, 1 - есть проблемы, 2 - конфиг не прочитан
+`
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run выполняет команду и возвращает код выхода
+func run(args []string) int {
+	if len(args) > 0 && args[0] == "backfill" {
+		return backfill(args[1:])
+	}
+	if len(args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	switch args[0] + " " + args[1] {
+	case "rules explain":
+		return rulesExplain(args[2:])
+	case "rules test":
+		return rulesTest(args[2:])
+	case "rules check":
+		return rulesCheck(args[2:])
+	case "config env":
+		return configEnv(args[2:])
+	case "config validate":
+		return configValidate(args[2:])
+	case "config schema":
+		return configSchema(args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "неизвестная команда: %s %s\n\n%s", args[0], args[1], usage)
+		return 2
+	}
+}
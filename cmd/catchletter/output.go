@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// ruleMatch - сработавшее правило в выводе команд
+type ruleMatch struct {
+	Rule        string `json:"rule"`
+	Score       int    `json:"score"`
+	MinScore    int    `json:"min_score"`
+	Level       string `json:"level,omitempty"`
+	LevelReason string `json:"level_reason,omitempty"`
+	NearMiss    bool   `json:"near_miss,omitempty"`
+}
+
+// emailResult - результат проверки одного письма
+type emailResult struct {
+	Source  string      `json:"source"`
+	Subject string      `json:"subject"`
+	From    string      `json:"from"`
+	Matches []ruleMatch `json:"matches"`
+}
+
+// evaluateEmail прогоняет письмо через правила. Возвращает результат для вывода
+// и сработавшие алерты (без почти сработавших)
+func evaluateEmail(engine *filter.Engine, source string, email *models.Email) (emailResult, []*models.Alert) {
+	result := emailResult{
+		Source:  source,
+		Subject: email.Subject,
+		From:    email.From,
+		Matches: make([]ruleMatch, 0),
+	}
+
+	alerts, nearMisses := engine.Evaluate(email)
+	for _, alert := range append(alerts, nearMisses...) {
+		match := ruleMatch{
+			Rule:     alert.Rule.Name,
+			Score:    alert.Score,
+			MinScore: alert.Rule.MinScore,
+			NearMiss: alert.NearMiss,
+		}
+		if !alert.NearMiss {
+			match.Level = alert.Level.String()
+			match.LevelReason = alert.LevelReason
+		}
+		result.Matches = append(result.Matches, match)
+	}
+	return result, alerts
+}
+
+// checkFormat проверяет значение флага -format
+func checkFormat(format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("неизвестный формат: %s", format)
+	}
+	return nil
+}
+
+// writeResults выводит результаты таблицей или JSON
+func writeResults(format string, results []emailResult) error {
+	if format == "json" {
+		return writeJSON(results)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ПИСЬМО\tТЕМА\tПРАВИЛО\tБАЛЛЫ\tУРОВЕНЬ")
+	for _, result := range results {
+		if len(result.Matches) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t—\t\t\n", result.Source, result.Subject)
+		}
+		for _, match := range result.Matches {
+			level := match.Level
+			if match.NearMiss {
+				level = "почти (дайджест)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%s\n",
+				result.Source, result.Subject, match.Rule, match.Score, match.MinScore, level)
+		}
+	}
+	return tw.Flush()
+}
+
+// writeJSON выводит v в stdout с отступами, не экранируя <> в адресах
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
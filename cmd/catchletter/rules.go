@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// headerFlags - повторяемый флаг -header "Name: value"
+type headerFlags map[string]string
+
+func (h headerFlags) String() string { return fmt.Sprint(map[string]string(h)) }
+
+func (h headerFlags) Set(value string) error {
+	name, val, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("ожидается \"Name: value\", получено %q", value)
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(val)
+	return nil
+}
+
+// rulesExplain печатает разбор письма всеми правилами
+func rulesExplain(args []string) int {
+	fs := flag.NewFlagSet("rules explain", flag.ContinueOnError)
+	configPath := fs.String("config", "", "путь к конфигу (по умолчанию ищется в стандартных местах)")
+	email := models.NewEmail()
+	fs.StringVar(&email.Subject, "subject", "", "тема письма")
+	fs.StringVar(&email.From, "from", "", "отправитель")
+	fs.StringVar(&email.Body, "body", "", "текст письма")
+	fs.Var(headerFlags(email.Headers), "header", "заголовок \"Name: value\", можно повторять")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	engine, err := loadEngine(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	// Письмо из файла вместо флагов
+	if fs.NArg() > 0 {
+		inputs, err := readEmails(fs.Args())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		for _, input := range inputs {
+			fmt.Printf("== %s: %s\n", input.Source, input.Email.Subject)
+			for _, trace := range engine.Explain(input.Email) {
+				fmt.Println(trace.String())
+			}
+		}
+		return 0
+	}
+
+	for _, trace := range engine.Explain(email) {
+		fmt.Println(trace.String())
+	}
+	return 0
+}
+
+// rulesTest прогоняет правила по письмам из файлов
+func rulesTest(args []string) int {
+	fs := flag.NewFlagSet("rules test", flag.ContinueOnError)
+	configPath := fs.String("config", "", "путь к конфигу (по умолчанию ищется в стандартных местах)")
+	format := fs.String("format", "table", "формат вывода: table или json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := checkFormat(*format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	engine, err := loadEngine(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	inputs, err := readEmails(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	code := 0
+	results := make([]emailResult, 0, len(inputs))
+	for _, input := range inputs {
+		result, alerts := evaluateEmail(engine, input.Source, input.Email)
+		if len(alerts) == 0 {
+			code = 1
+		}
+		results = append(results, result)
+	}
+
+	if err := writeResults(*format, results); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return code
+}
+
+// loadEngine загружает конфиг и создаёт движок правил с теми же настройками, что и монитор
+func loadEngine(configPath string) (*filter.Engine, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки конфига: %w", err)
+	}
+	return filter.NewConfiguredEngine(cfg, cfg.Rules), nil
+}
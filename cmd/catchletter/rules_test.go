@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const rulesConfig = `imap:
+  server: imap.example.com
+  username: user
+  password: secret
+  port: 993
+rules:
+  - id: "rule-1"
+    name: "Медосмотр"
+    enabled: true
+    min_score: 50
+    conditions:
+      - {type: subject, operator: contains, value: "медосмотр", weight: 60}
+    actions: ["telegram"]
+`
+
+// writeRulesFiles пишет конфиг, письма и тесты правил во временный каталог
+func writeRulesFiles(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": rulesConfig,
+		"match.eml":   "From: med@hse.ru\r\nSubject: Запись на медосмотр\r\n\r\nТекст\r\n",
+		"miss.eml":    "From: news@hse.ru\r\nSubject: Новости\r\n\r\nТекст\r\n",
+		"pass.yaml": `cases:
+  - name: "Медосмотр"
+    email: {subject: "Запись на медосмотр", from: "med@hse.ru"}
+    expect: [{rule: "Медосмотр"}]
+`,
+		"fail.yaml": `cases:
+  - name: "Новости"
+    email: {subject: "Новости", from: "news@hse.ru"}
+    expect: [{rule: "Медосмотр"}]
+`,
+		"broken.yaml": `cases:
+  - name: "Нет письма"
+    eml: "missing.eml"
+    expect: []
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestRulesTestExitCode(t *testing.T) {
+	dir := writeRulesFiles(t)
+	config := filepath.Join(dir, "config.yaml")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"письмо сработало", []string{"-config", config, filepath.Join(dir, "match.eml")}, 0},
+		{"одно из писем не сработало", []string{"-config", config, filepath.Join(dir, "match.eml"), filepath.Join(dir, "miss.eml")}, 1},
+		{"неизвестный формат", []string{"-config", config, "-format", "xml", filepath.Join(dir, "match.eml")}, 2},
+		{"неизвестный флаг", []string{"-verbose"}, 2},
+		{"нет конфига", []string{"-config", filepath.Join(dir, "missing.yaml"), filepath.Join(dir, "match.eml")}, 2},
+		{"нет письма", []string{"-config", config, filepath.Join(dir, "missing.eml")}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rulesTest(tt.args); got != tt.expected {
+				t.Errorf("incorrect exit code, expected: %d, got: %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRulesCheckExitCode(t *testing.T) {
+	dir := writeRulesFiles(t)
+	config := filepath.Join(dir, "config.yaml")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"все тесты прошли", []string{"-config", config, filepath.Join(dir, "pass.yaml")}, 0},
+		{"есть расхождения", []string{"-config", config, filepath.Join(dir, "pass.yaml"), filepath.Join(dir, "fail.yaml")}, 1},
+		{"ошибка в тесте важнее расхождения", []string{"-config", config, filepath.Join(dir, "fail.yaml"), filepath.Join(dir, "broken.yaml")}, 2},
+		{"нет файла тестов", []string{"-config", config, filepath.Join(dir, "missing.yaml")}, 2},
+		{"нет конфига", []string{"-config", filepath.Join(dir, "missing.yaml"), filepath.Join(dir, "pass.yaml")}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rulesCheck(tt.args); got != tt.expected {
+				t.Errorf("incorrect exit code, expected: %d, got: %d", tt.expected, got)
+			}
+		})
+	}
+}
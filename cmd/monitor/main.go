@@ -2,17 +2,25 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/Strochik12/CatchAnImportantLetter/internal/api"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/processor"
 )
 
 func main() {
+	var opts processor.Options
+	flag.BoolVar(&opts.DryRun, "dry-run", false, "не отправлять уведомления и не сохранять состояние ящика")
+	flag.StringVar(&opts.ReportPath, "report", "", "файл JSON Lines для алертов dry-run и расхождений shadow-правил")
+	flag.StringVar(&opts.ShadowRules, "shadow-rules", "", "файл с правилами-кандидатами для сравнения с боевыми")
+	flag.Parse()
+
 	log.Println("📧 HSE Email Alert System")
 	log.Println("==========================")
 
@@ -22,7 +30,7 @@ func main() {
 	}
 	log.Println("Конфигурация загружена")
 
-	proc, err := processor.NewProcessor(cfg)
+	proc, err := processor.NewProcessor(cfg, opts)
 	if err != nil {
 		log.Fatalf("Ошибка инициализации системы: %v", err)
 	}
@@ -33,20 +41,59 @@ func main() {
 	defer stop()
 
 	// Таймер для переодического вывода статистики
-	statsTicker := time.NewTicker(5 * time.Minute)
-	defer statsTicker.Stop()
+	if minutes := cfg.Monitoring.StatsIntervalMinutes; minutes > 0 {
+		statsTicker := time.NewTicker(time.Duration(minutes) * time.Minute)
+		defer statsTicker.Stop()
+
+		go func() {
+			for {
+				select {
+				case <-statsTicker.C:
+					proc.PrintStats()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	// Перезагрузка конфигурации без перезапуска: по SIGHUP и при изменении файла
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
 
 	go func() {
 		for {
 			select {
-			case <-statsTicker.C:
-				proc.PrintStats()
+			case <-reload:
+				log.Println("Получен SIGHUP, перезагружаем конфигурацию")
+				proc.Reload()
 			case <-ctx.Done():
 				return
 			}
 		}
 	}()
 
+	go func() {
+		err := config.Watch(ctx, cfg.Files(), func() {
+			log.Println("Файл конфигурации изменён, перезагружаем")
+			proc.Reload()
+		})
+		if err != nil {
+			log.Printf("Наблюдение за конфигом выключено: %v", err)
+		}
+	}()
+
+	// HTTP API состояния и управления
+	if cfg.API.Enabled {
+		server := api.New(&cfg.API, proc)
+		go func() {
+			if err := server.Run(ctx); err != nil {
+				log.Printf("Ошибка HTTP API: %v", err)
+			}
+		}()
+	}
+
 	// Запускаем систему
 	log.Println("🚀 Запускаем мониторинг почты...")
 	log.Println("----------------------------------------")
@@ -7,6 +7,7 @@ import (
 	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/notifier"
 )
 
 func main() {
@@ -38,7 +39,7 @@ func main() {
 		fmt.Println("	Не создано алертов")
 	} else {
 		for i, alert := range alerts {
-			fmt.Printf("  %d. %s\n", i+1, alert.Message)
+			fmt.Printf("  %d. %s\n", i+1, notifier.Message(alert))
 		}
 	}
 
@@ -41,7 +41,7 @@ func main() {
 	// Отправляем через менеджер
 	if manager.HasNotifier(models.ActionNotifyTelegram) {
 		log.Println("Отправляю тестовое уведомление через менеджер...")
-		if err := manager.Send(models.ActionNotifyTelegram, testAlert); err != nil {
+		if err := manager.Send(models.Action{Type: models.ActionNotifyTelegram}, testAlert); err != nil {
 			log.Fatalf("Ошибка отправки: %v", err)
 		}
 		log.Println("Уведомление отправлено!")
@@ -0,0 +1,493 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/history"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/metrics"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/outbox"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/processor"
+)
+
+const (
+	defaultAlertsLimit = 50
+	maxAlertsLimit     = 500
+	shutdownTimeout    = 5 * time.Second
+)
+
+// Backend - то, чем управляет API. Реализуется processor.Processor
+type Backend interface {
+	Ready() bool
+	GetStats() *processor.Stats
+	Paused() bool
+	Pause()
+	Resume()
+	CheckNow()
+	Rules() []models.Rule
+	SetRuleEnabled(idOrName string, enabled bool) (models.Rule, error)
+	UpdateRule(idOrName string, rule models.Rule) (models.Rule, error)
+	ExplainRule(rule models.Rule, emailID models.ID) (filter.RuleTrace, error)
+	RecentAlerts(q history.Query) ([]history.AlertRecord, error)
+	RecentEmails() []*models.Email
+	DeadNotifications() []outbox.Entry
+	RequeueDead(ids ...models.ID) (int, error)
+	PurgeDead(ids ...models.ID) (int, error)
+}
+
+// Server - HTTP сервер состояния и управления монитором
+type Server struct {
+	listen  string
+	token   string
+	backend Backend
+	started time.Time
+	handler http.Handler
+}
+
+// New создаёт сервер. Маршруты:
+//
+//	GET  /healthz                    - процесс жив
+//	GET  /readyz                     - подключён к почте и обрабатывает письма
+//	GET  /metrics                    - метрики в формате Prometheus
+//	GET  /api/stats                  - статистика и счётчики правил
+//	GET  /ui/                        - веб-интерфейс: правила и история алертов
+//	GET  /api/alerts                 - последние алерты (?limit=&rule=&since=&until=RFC3339)
+//	GET  /api/emails                 - последние письма для проверки правил
+//	GET  /api/rules                  - загруженные правила
+//	PUT  /api/rules/{id}             - изменить правило, сохранить в конфиг и применить
+//	POST /api/explain                - разобрать письмо правилом: {"rule": {...}, "email_id": "..."}
+//	POST /api/pause, /api/resume     - приостановить и возобновить проверки
+//	POST /api/check                  - внеочередная проверка почты
+//	POST /api/rules/{id}/enable      - включить правило по ID или имени
+//	POST /api/rules/{id}/disable     - выключить правило
+//	GET  /api/outbox/dead            - недоставленные уведомления
+//	POST /api/outbox/dead/requeue    - отправить недоставленные заново (?id= - только эти)
+//	DELETE /api/outbox/dead          - удалить недоставленные (?id= - только эти)
+func New(cfg *config.APIConfig, backend Backend) *Server {
+	s := &Server{
+		listen:  cfg.Listen,
+		token:   cfg.Token,
+		backend: backend,
+		started: time.Now(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.health)
+	mux.HandleFunc("GET /readyz", s.ready)
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+	mux.HandleFunc("GET /api/stats", s.stats)
+	mux.HandleFunc("GET /api/alerts", s.alerts)
+	mux.HandleFunc("GET /api/rules", s.rules)
+	mux.HandleFunc("GET /api/emails", s.emails)
+	mux.HandleFunc("PUT /api/rules/{id}", s.authorized(s.updateRule))
+	mux.HandleFunc("POST /api/explain", s.authorized(s.explain))
+	mux.Handle("GET /ui/", http.StripPrefix("/ui/", http.FileServerFS(webFS())))
+	mux.Handle("GET /{$}", http.RedirectHandler("/ui/", http.StatusFound))
+	mux.HandleFunc("POST /api/pause", s.authorized(s.pause))
+	mux.HandleFunc("POST /api/resume", s.authorized(s.resume))
+	mux.HandleFunc("POST /api/check", s.authorized(s.check))
+	mux.HandleFunc("POST /api/rules/{id}/enable", s.authorized(s.setRuleEnabled(true)))
+	mux.HandleFunc("POST /api/rules/{id}/disable", s.authorized(s.setRuleEnabled(false)))
+	mux.HandleFunc("GET /api/outbox/dead", s.deadNotifications)
+	mux.HandleFunc("POST /api/outbox/dead/requeue", s.authorized(s.requeueDead))
+	mux.HandleFunc("DELETE /api/outbox/dead", s.authorized(s.purgeDead))
+	s.handler = mux
+
+	return s
+}
+
+// Handler возвращает обработчик всех маршрутов
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Run слушает адрес из конфига, пока не отменён ctx
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.listen,
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if s.token == "" {
+		log.Printf("HTTP API слушает %s (только чтение: token не задан)", s.listen)
+	} else {
+		log.Printf("HTTP API слушает %s", s.listen)
+	}
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// statsResponse - ответ /api/stats
+type statsResponse struct {
+	EmailsProcessed   int                             `json:"emails_processed"`
+	AlertsGenerated   int                             `json:"alerts_generated"`
+	NotificationsSent int                             `json:"notifications_sent"`
+	LastActivity      time.Time                       `json:"last_activity"`
+	Errors            []string                        `json:"errors"`
+	ErrorsTotal       int                             `json:"errors_total"`
+	Rules             map[string]*processor.RuleStats `json:"rules"`
+	Paused            bool                            `json:"paused"`
+	Uptime            string                          `json:"uptime"`
+}
+
+// alertResponse - алерт в ответе /api/alerts
+type alertResponse struct {
+	history.AlertRecord
+	Level string `json:"level"`
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) ready(w http.ResponseWriter, r *http.Request) {
+	if !s.backend.Ready() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not ready"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	stats := s.backend.GetStats()
+
+	resp := statsResponse{
+		EmailsProcessed:   stats.EmailsProcessed,
+		AlertsGenerated:   stats.AlertsGenerated,
+		NotificationsSent: stats.NotificationsSent,
+		LastActivity:      stats.LastActivity,
+		Errors:            make([]string, 0, len(stats.Errors)),
+		ErrorsTotal:       stats.ErrorsTotal,
+		Rules:             stats.Rules,
+		Paused:            s.backend.Paused(),
+		Uptime:            time.Since(s.started).Round(time.Second).String(),
+	}
+	for _, err := range stats.Errors {
+		resp.Errors = append(resp.Errors, err.Error())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) alerts(w http.ResponseWriter, r *http.Request) {
+	q := history.Query{
+		Rule:  r.URL.Query().Get("rule"),
+		Limit: defaultAlertsLimit,
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit должен быть положительным числом")
+			return
+		}
+		q.Limit = min(n, maxAlertsLimit)
+	}
+	for param, target := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, param+" должен быть в формате RFC3339")
+			return
+		}
+		*target = t
+	}
+
+	records, err := s.backend.RecentAlerts(q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	alerts := make([]alertResponse, 0, len(records))
+	for _, record := range records {
+		alerts = append(alerts, alertResponse{AlertRecord: record, Level: record.Level.String()})
+	}
+	writeJSON(w, http.StatusOK, alerts)
+}
+
+func (s *Server) rules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.backend.Rules())
+}
+
+// emailResponse - письмо в ответе /api/emails, без текста
+type emailResponse struct {
+	ID      models.ID `json:"id"`
+	From    string    `json:"from"`
+	Subject string    `json:"subject"`
+	Date    time.Time `json:"date"`
+}
+
+func (s *Server) emails(w http.ResponseWriter, r *http.Request) {
+	emails := make([]emailResponse, 0)
+	for _, email := range s.backend.RecentEmails() {
+		emails = append(emails, emailResponse{
+			ID:      email.ID,
+			From:    email.From,
+			Subject: email.Subject,
+			Date:    email.Date,
+		})
+	}
+	writeJSON(w, http.StatusOK, emails)
+}
+
+func (s *Server) updateRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.Rule
+	if err := decodeJSON(r, &rule); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := s.backend.UpdateRule(r.PathValue("id"), rule)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// explainRequest - тело /api/explain
+type explainRequest struct {
+	Rule    models.Rule `json:"rule"`
+	EmailID models.ID   `json:"email_id"`
+}
+
+// conditionResponse - условие в разборе правила
+type conditionResponse struct {
+	Reason  string `json:"reason"`
+	Excerpt string `json:"excerpt"`
+	Matched bool   `json:"matched"`
+	Added   int    `json:"added"`
+	Error   string `json:"error,omitempty"`
+}
+
+// traceResponse - ответ /api/explain
+type traceResponse struct {
+	Rule        string              `json:"rule"`
+	Matched     bool                `json:"matched"`
+	NearMiss    bool                `json:"near_miss"`
+	Score       int                 `json:"score"`
+	RawScore    int                 `json:"raw_score"`
+	MinScore    int                 `json:"min_score"`
+	Level       string              `json:"level,omitempty"`
+	LevelReason string              `json:"level_reason,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	Conditions  []conditionResponse `json:"conditions"`
+	Text        string              `json:"text"`
+}
+
+func (s *Server) explain(w http.ResponseWriter, r *http.Request) {
+	var req explainRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	trace, err := s.backend.ExplainRule(req.Rule, req.EmailID)
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	resp := traceResponse{
+		Rule:        trace.Rule.Name,
+		Matched:     trace.Matched,
+		NearMiss:    trace.NearMiss,
+		Score:       trace.Score,
+		RawScore:    trace.RawScore,
+		MinScore:    trace.Rule.MinScore,
+		LevelReason: trace.LevelReason,
+		Conditions:  make([]conditionResponse, 0, len(trace.Conditions)),
+		Text:        trace.String(),
+	}
+	if trace.Matched {
+		resp.Level = trace.Level.String()
+	}
+	if trace.Err != nil {
+		resp.Error = trace.Err.Error()
+	}
+	for _, cond := range trace.Conditions {
+		c := conditionResponse{
+			Reason:  cond.Reason(),
+			Excerpt: cond.Excerpt,
+			Matched: cond.Matched,
+			Added:   cond.Added,
+		}
+		if cond.Err != nil {
+			c.Error = cond.Err.Error()
+		}
+		resp.Conditions = append(resp.Conditions, c)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) pause(w http.ResponseWriter, r *http.Request) {
+	s.backend.Pause()
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+func (s *Server) resume(w http.ResponseWriter, r *http.Request) {
+	s.backend.Resume()
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+func (s *Server) check(w http.ResponseWriter, r *http.Request) {
+	s.backend.CheckNow()
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "check requested"})
+}
+
+// deadResponse - недоставленное уведомление в ответе /api/outbox/dead, без текста письма
+type deadResponse struct {
+	ID        models.ID `json:"id"`
+	Rule      string    `json:"rule,omitempty"`
+	Action    string    `json:"action"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	DeadAt    time.Time `json:"dead_at,omitzero"`
+	From      string    `json:"from,omitempty"`
+	Subject   string    `json:"subject,omitempty"`
+}
+
+func (s *Server) deadNotifications(w http.ResponseWriter, r *http.Request) {
+	dead := make([]deadResponse, 0)
+	for _, entry := range s.backend.DeadNotifications() {
+		item := deadResponse{
+			ID:        entry.ID,
+			Action:    entry.Action.String(),
+			Attempts:  entry.Attempts,
+			LastError: entry.LastError,
+			DeadAt:    entry.DeadAt,
+		}
+		if alert := entry.Alert; alert != nil {
+			if alert.Rule != nil {
+				item.Rule = alert.Rule.Name
+			}
+			if alert.Email != nil {
+				item.From = alert.Email.From
+				item.Subject = alert.Email.Subject
+			}
+		}
+		dead = append(dead, item)
+	}
+	writeJSON(w, http.StatusOK, dead)
+}
+
+func (s *Server) requeueDead(w http.ResponseWriter, r *http.Request) {
+	n, err := s.backend.RequeueDead(entryIDs(r)...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"requeued": n})
+}
+
+func (s *Server) purgeDead(w http.ResponseWriter, r *http.Request) {
+	n, err := s.backend.PurgeDead(entryIDs(r)...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"purged": n})
+}
+
+// entryIDs возвращает ID записей очереди из параметров ?id=
+func entryIDs(r *http.Request) []models.ID {
+	var ids []models.ID
+	for _, id := range r.URL.Query()["id"] {
+		ids = append(ids, models.ID(id))
+	}
+	return ids
+}
+
+func (s *Server) setRuleEnabled(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rule, err := s.backend.SetRuleEnabled(r.PathValue("id"), enabled)
+		if err != nil {
+			writeError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, rule)
+	}
+}
+
+// authorized пропускает запрос только с верным Bearer токеном
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token == "" {
+			writeError(w, http.StatusForbidden, "управление выключено: в конфиге не задан api.token")
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "неверный токен")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// maxBodySize - ограничение тела запроса
+const maxBodySize = 1 << 20
+
+// decodeJSON читает тело запроса, неизвестные поля - ошибка (скорее всего опечатка)
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("неверный JSON: %w", err)
+	}
+	return nil
+}
+
+// errorStatus подбирает HTTP статус для ошибки обработчика
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, processor.ErrRuleNotFound), errors.Is(err, processor.ErrEmailNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, processor.ErrInvalidRule):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		log.Printf("Ошибка записи ответа API: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/history"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/outbox"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/processor"
+)
+
+type fakeBackend struct {
+	ready   bool
+	paused  bool
+	checks  int
+	rules   []models.Rule
+	alerts  []history.AlertRecord
+	lastQ   history.Query
+	errorsN int
+	dead    []outbox.Entry
+}
+
+func (f *fakeBackend) Ready() bool  { return f.ready }
+func (f *fakeBackend) Paused() bool { return f.paused }
+func (f *fakeBackend) Pause()       { f.paused = true }
+func (f *fakeBackend) Resume()      { f.paused = false }
+func (f *fakeBackend) CheckNow()    { f.checks++ }
+
+func (f *fakeBackend) GetStats() *processor.Stats {
+	return &processor.Stats{
+		EmailsProcessed: 3,
+		Errors:          []error{errors.New("ошибка")},
+		ErrorsTotal:     f.errorsN,
+		Rules:           map[string]*processor.RuleStats{"Медосмотр": {Alerts: 2}},
+	}
+}
+
+func (f *fakeBackend) Rules() []models.Rule { return f.rules }
+
+func (f *fakeBackend) SetRuleEnabled(idOrName string, enabled bool) (models.Rule, error) {
+	for i := range f.rules {
+		if string(f.rules[i].ID) == idOrName {
+			f.rules[i].Enabled = enabled
+			return f.rules[i], nil
+		}
+	}
+	return models.Rule{}, fmt.Errorf("%w: %s", processor.ErrRuleNotFound, idOrName)
+}
+
+func (f *fakeBackend) UpdateRule(idOrName string, rule models.Rule) (models.Rule, error) {
+	if rule.MinScore < 0 {
+		return models.Rule{}, fmt.Errorf("%w: min_score must be non-negative", processor.ErrInvalidRule)
+	}
+	for i := range f.rules {
+		if string(f.rules[i].ID) == idOrName {
+			rule.ID = f.rules[i].ID
+			f.rules[i] = rule
+			return rule, nil
+		}
+	}
+	return models.Rule{}, fmt.Errorf("%w: %s", processor.ErrRuleNotFound, idOrName)
+}
+
+func (f *fakeBackend) ExplainRule(rule models.Rule, emailID models.ID) (filter.RuleTrace, error) {
+	if emailID != "e1" {
+		return filter.RuleTrace{}, fmt.Errorf("%w: %s", processor.ErrEmailNotFound, emailID)
+	}
+	return filter.RuleTrace{Rule: &rule, Matched: true, Score: 42, Level: models.AlertHigh}, nil
+}
+
+func (f *fakeBackend) RecentEmails() []*models.Email {
+	return []*models.Email{{ID: "e1", From: "hr@example.com", Subject: "Медосмотр"}}
+}
+
+func (f *fakeBackend) RecentAlerts(q history.Query) ([]history.AlertRecord, error) {
+	f.lastQ = q
+	return f.alerts, nil
+}
+
+func (f *fakeBackend) DeadNotifications() []outbox.Entry { return f.dead }
+
+func (f *fakeBackend) RequeueDead(ids ...models.ID) (int, error) {
+	return f.PurgeDead(ids...)
+}
+
+func (f *fakeBackend) PurgeDead(ids ...models.ID) (int, error) {
+	kept := f.dead[:0]
+	for _, entry := range f.dead {
+		if len(ids) > 0 && !slices.Contains(ids, entry.ID) {
+			kept = append(kept, entry)
+		}
+	}
+	n := len(f.dead) - len(kept)
+	f.dead = kept
+	return n, nil
+}
+
+func testServer(token string) (*Server, *fakeBackend) {
+	backend := &fakeBackend{
+		rules:   []models.Rule{{ID: "rule-1", Name: "Медосмотр", Enabled: true}},
+		alerts:  []history.AlertRecord{{ID: "a1", Rule: "Медосмотр", Level: models.AlertHigh}},
+		errorsN: 7,
+		dead: []outbox.Entry{
+			{ID: "o1", State: outbox.StateDead, Alert: &models.Alert{
+				Rule:  &models.Rule{Name: "Медосмотр"},
+				Email: &models.Email{From: "med@hse.ru", Subject: "Запись", Body: "Личные данные"},
+			}},
+			{ID: "o2", State: outbox.StateDead},
+		},
+	}
+	return New(&config.APIConfig{Enabled: true, Listen: "127.0.0.1:0", Token: token}, backend), backend
+}
+
+func do(s *Server, method, path, token string) *httptest.ResponseRecorder {
+	return doJSON(s, method, path, token, nil)
+}
+
+func doJSON(s *Server, method, path, token string, body any) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReadOnlyEndpoints(t *testing.T) {
+	s, backend := testServer("secret")
+
+	if rec := do(s, http.MethodGet, "/healthz", ""); rec.Code != http.StatusOK {
+		t.Errorf("healthz: expected 200, got: %d", rec.Code)
+	}
+	if rec := do(s, http.MethodGet, "/readyz", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz: expected 503 before ready, got: %d", rec.Code)
+	}
+	backend.ready = true
+	if rec := do(s, http.MethodGet, "/readyz", ""); rec.Code != http.StatusOK {
+		t.Errorf("readyz: expected 200, got: %d", rec.Code)
+	}
+
+	rec := do(s, http.MethodGet, "/api/stats", "")
+	var stats statsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.EmailsProcessed != 3 || stats.ErrorsTotal != 7 || len(stats.Errors) != 1 || stats.Rules["Медосмотр"].Alerts != 2 {
+		t.Errorf("incorrect stats: %+v", stats)
+	}
+
+	rec = do(s, http.MethodGet, "/api/alerts?limit=10&rule=Медосмотр", "")
+	var alerts []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &alerts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0]["level"] != "high" {
+		t.Errorf("incorrect alerts: %v", alerts)
+	}
+	if backend.lastQ.Limit != 10 || backend.lastQ.Rule != "Медосмотр" {
+		t.Errorf("incorrect query: %+v", backend.lastQ)
+	}
+
+	if rec := do(s, http.MethodGet, "/api/alerts?limit=abc", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for bad limit, got: %d", rec.Code)
+	}
+}
+
+func TestControlRequiresToken(t *testing.T) {
+	s, backend := testServer("secret")
+
+	if rec := do(s, http.MethodPost, "/api/pause", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got: %d", rec.Code)
+	}
+	if rec := do(s, http.MethodPost, "/api/pause", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got: %d", rec.Code)
+	}
+	if backend.paused {
+		t.Fatal("monitor must not be paused without valid token")
+	}
+
+	if rec := do(s, http.MethodPost, "/api/pause", "secret"); rec.Code != http.StatusOK || !backend.paused {
+		t.Errorf("expected pause, got: %d", rec.Code)
+	}
+	do(s, http.MethodPost, "/api/resume", "secret")
+	if backend.paused {
+		t.Error("expected resume")
+	}
+	if rec := do(s, http.MethodPost, "/api/check", "secret"); rec.Code != http.StatusAccepted || backend.checks != 1 {
+		t.Errorf("expected check request, got: %d", rec.Code)
+	}
+
+	if rec := do(s, http.MethodPost, "/api/rules/rule-1/disable", "secret"); rec.Code != http.StatusOK || backend.rules[0].Enabled {
+		t.Errorf("expected rule to be disabled, got: %d", rec.Code)
+	}
+	if rec := do(s, http.MethodPost, "/api/rules/nope/enable", "secret"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown rule, got: %d", rec.Code)
+	}
+	if rec := do(s, http.MethodGet, "/api/pause", "secret"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got: %d", rec.Code)
+	}
+}
+
+func TestControlDisabledWithoutToken(t *testing.T) {
+	s, backend := testServer("")
+
+	if rec := do(s, http.MethodPost, "/api/pause", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without configured token, got: %d", rec.Code)
+	}
+	if backend.paused {
+		t.Error("monitor must not be paused")
+	}
+}
+
+func TestUpdateRule(t *testing.T) {
+	s, backend := testServer("secret")
+
+	rule := models.Rule{Name: "Медосмотр", Enabled: true, MinScore: 30}
+	if rec := doJSON(s, http.MethodPut, "/api/rules/rule-1", "", rule); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got: %d", rec.Code)
+	}
+
+	rec := doJSON(s, http.MethodPut, "/api/rules/rule-1", "secret", rule)
+	if rec.Code != http.StatusOK || backend.rules[0].MinScore != 30 {
+		t.Errorf("expected rule to be updated, got: %d %s", rec.Code, rec.Body)
+	}
+
+	rule.MinScore = -1
+	if rec := doJSON(s, http.MethodPut, "/api/rules/rule-1", "secret", rule); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for invalid rule, got: %d", rec.Code)
+	}
+	if rec := doJSON(s, http.MethodPut, "/api/rules/nope", "secret", models.Rule{}); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown rule, got: %d", rec.Code)
+	}
+	if rec := doJSON(s, http.MethodPut, "/api/rules/rule-1", "secret", map[string]any{"min_scor": 10}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown field, got: %d", rec.Code)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	s, _ := testServer("secret")
+
+	rec := do(s, http.MethodGet, "/api/emails", "")
+	var emails []emailResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &emails); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(emails) != 1 || emails[0].ID != "e1" {
+		t.Errorf("incorrect emails: %+v", emails)
+	}
+
+	req := explainRequest{Rule: models.Rule{Name: "Медосмотр", MinScore: 30}, EmailID: "e1"}
+	rec = doJSON(s, http.MethodPost, "/api/explain", "secret", req)
+	var trace traceResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &trace); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !trace.Matched || trace.Score != 42 || trace.Level != "high" || trace.MinScore != 30 || trace.Text == "" {
+		t.Errorf("incorrect trace: %+v", trace)
+	}
+
+	req.EmailID = "nope"
+	if rec := doJSON(s, http.MethodPost, "/api/explain", "secret", req); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown email, got: %d", rec.Code)
+	}
+}
+
+func TestUI(t *testing.T) {
+	s, _ := testServer("")
+
+	if rec := do(s, http.MethodGet, "/", ""); rec.Code != http.StatusFound || rec.Header().Get("Location") != "/ui/" {
+		t.Errorf("expected redirect to /ui/, got: %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+	rec := do(s, http.MethodGet, "/ui/", "")
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte("/api/rules")) {
+		t.Errorf("expected UI page, got: %d", rec.Code)
+	}
+}
+
+func TestDeadNotifications(t *testing.T) {
+	s, backend := testServer("secret")
+
+	rec := do(s, http.MethodGet, "/api/outbox/dead", "")
+	var dead []deadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &dead); err != nil || len(dead) != 2 {
+		t.Fatalf("expected 2 dead entries, got: %s", rec.Body)
+	}
+	if dead[0].Rule != "Медосмотр" || dead[0].Subject != "Запись" || strings.Contains(rec.Body.String(), "Личные данные") {
+		t.Errorf("expected metadata without email body, got: %s", rec.Body)
+	}
+
+	if rec := do(s, http.MethodPost, "/api/outbox/dead/requeue?id=o1", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got: %d", rec.Code)
+	}
+	rec = do(s, http.MethodPost, "/api/outbox/dead/requeue?id=o1", "secret")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"requeued":1`) {
+		t.Errorf("expected 1 requeued entry, got: %d %s", rec.Code, rec.Body)
+	}
+	rec = do(s, http.MethodDelete, "/api/outbox/dead", "secret")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"purged":1`) || len(backend.dead) != 0 {
+		t.Errorf("expected 1 purged entry, got: %d %s", rec.Code, rec.Body)
+	}
+}
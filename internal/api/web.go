@@ -0,0 +1,18 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// webFS возвращает файлы веб-интерфейса без префикса web/
+func webFS() fs.FS {
+	sub, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
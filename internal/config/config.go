@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
@@ -12,18 +14,181 @@ type Config struct {
 	Rules      []*models.Rule   `yaml:"rules"`
 	Monitoring MonitoringConfig `yaml:"monitoring,omitempty"`
 	Notifiers  NotifiersConfig  `yaml:"notifiers,omitempty"`
+	Outbox     OutboxConfig     `yaml:"outbox,omitempty"`
+	Digest     DigestConfig     `yaml:"digest,omitempty"`
+	Dedup      DedupConfig      `yaml:"dedup,omitempty"`
+	Scoring    ScoringConfig    `yaml:"scoring,omitempty"`
+	History    HistoryConfig    `yaml:"history,omitempty"`
+	API        APIConfig        `yaml:"api,omitempty"`
+
+	// Include - дополнительные файлы с блоком rules, можно шаблоном: "rules/*.yaml".
+	// Относительные пути считаются от каталога основного конфига
+	Include []string `yaml:"include,omitempty"`
+	// RulesDir - каталог, из которого загружаются правила из всех *.yaml и *.yml
+	RulesDir string `yaml:"rules_dir,omitempty"`
+
+	// Path - файл, из которого загружен конфиг. Туда же сохраняются правила из веб-интерфейса
+	Path string `yaml:"-"`
+	// IncludedFiles - подключённые файлы правил из include и rules_dir, в том числе пустые
+	IncludedFiles []string `yaml:"-"`
+	// RuleSources - где описано каждое правило из Rules (по тому же индексу)
+	RuleSources []RuleSource `yaml:"-"`
+
+	// positions - где в файлах записана каждая настройка (ключ - путь вида rules[0].min_score),
+	// чтобы ошибки проверки указывали строку и столбец
+	positions map[string]Position
+}
+
+// RuleSource - файл, в котором описано правило, и номер правила в его списке rules.
+// Правило находится в файле и без id, и после переименования
+type RuleSource struct {
+	File  string
+	Index int
+}
+
+// RuleSource возвращает, где описано правило с номером index в Rules.
+// Конфиг, собранный не из файлов, считается одним файлом c.Path
+func (c *Config) RuleSource(index int) RuleSource {
+	if index < len(c.RuleSources) {
+		return c.RuleSources[index]
+	}
+	return RuleSource{File: c.Path, Index: index}
+}
+
+// Files возвращает файлы и каталоги, из которых собран конфиг, для наблюдения за изменениями
+func (c *Config) Files() []string {
+	files := append([]string{c.Path}, c.IncludedFiles...)
+	if c.RulesDir != "" {
+		files = append(files, resolvePath(c.Path, c.RulesDir))
+	}
+	return files
+}
+
+// ScoringConfig - модель подсчёта баллов и уровней важности
+type ScoringConfig struct {
+	// Normalize - баллы приводятся к 0-100 (доля от суммы весов правила),
+	// min_score и пороги уровней тогда тоже задаются в 0-100
+	Normalize bool `yaml:"normalize,omitempty"`
+	// Levels - общие пороги уровней. Правило может задать свои, по умолчанию - от min_score
+	Levels *models.LevelThresholds `yaml:"levels,omitempty"`
+	// PriorityBoost - правила с priority не ниже этого поднимают уровень на ступень, 0 - выключено
+	PriorityBoost int `yaml:"priority_boost,omitempty"`
 }
 
 type NotifiersConfig struct {
-	Telegram *TelegramConfig `yaml:"telegram,omitempty"`
+	Telegram   *TelegramConfig   `yaml:"telegram,omitempty"`
+	Slack      *SlackConfig      `yaml:"slack,omitempty"`
+	Mattermost *MattermostConfig `yaml:"mattermost,omitempty"`
+	Discord    *DiscordConfig    `yaml:"discord,omitempty"`
+	Matrix     *MatrixConfig     `yaml:"matrix,omitempty"`
+	Ntfy       *NtfyConfig       `yaml:"ntfy,omitempty"`
+	Gotify     *GotifyConfig     `yaml:"gotify,omitempty"`
+	Desktop    *DesktopConfig    `yaml:"desktop,omitempty"`
 	// SMS      *SMSConfig      `yaml:"sms,omitempty"`
-	// Webhook  *WebhookConfig  `yaml:"webhook,omitempty"`
+
+	// RateLimits - ограничения частоты по типу нотификатора. Критичные алерты их не учитывают
+	RateLimits map[models.ActionType]RateLimitConfig `yaml:"rate_limits,omitempty"`
+
+	// QuietHours - тихие часы для всех нотификаторов
+	QuietHours *QuietHoursConfig `yaml:"quiet_hours,omitempty"`
+}
+
+// RateLimitConfig - token bucket: burst сообщений подряд, дальше per_minute в минуту
+type RateLimitConfig struct {
+	PerMinute float64 `yaml:"per_minute"`
+	Burst     int     `yaml:"burst"`
 }
 
 type TelegramConfig struct {
 	Enabled  bool   `yaml:"enabled,omitempty"`
 	BotToken string `yaml:"bot_token"`
 	ChatID   int64  `yaml:"chat_id"`
+	Format   string `yaml:"format,omitempty"`   // html (по умолчанию), markdownv2 или plain
+	Template string `yaml:"template,omitempty"` // text/template, по умолчанию встроенный
+	// Targets - именованные получатели для действий вида "telegram:имя"
+	Targets map[string]TelegramTarget `yaml:"targets,omitempty"`
+}
+
+// TelegramTarget - чат и, для групп с темами, тема (message_thread_id)
+type TelegramTarget struct {
+	ChatID   int64 `yaml:"chat_id"`
+	ThreadID int   `yaml:"thread_id,omitempty"`
+}
+
+// SlackConfig - настройки Slack incoming webhook
+type SlackConfig struct {
+	Enabled    bool   `yaml:"enabled,omitempty"`
+	WebhookURL string `yaml:"webhook_url"`
+	Channel    string `yaml:"channel,omitempty"`
+	Username   string `yaml:"username,omitempty"`
+	Template   string `yaml:"template,omitempty"`
+	// Targets - именованные получатели для действий вида "slack:имя"
+	Targets map[string]WebhookTarget `yaml:"targets,omitempty"`
+}
+
+// WebhookTarget - другой webhook и/или канал. Пустые поля берутся из основных настроек
+type WebhookTarget struct {
+	WebhookURL string `yaml:"webhook_url,omitempty"`
+	Channel    string `yaml:"channel,omitempty"`
+}
+
+// MattermostConfig - настройки Mattermost incoming webhook (Slack-совместимый формат)
+type MattermostConfig struct {
+	Enabled    bool                     `yaml:"enabled,omitempty"`
+	WebhookURL string                   `yaml:"webhook_url"`
+	Channel    string                   `yaml:"channel,omitempty"`
+	Username   string                   `yaml:"username,omitempty"`
+	Template   string                   `yaml:"template,omitempty"`
+	Targets    map[string]WebhookTarget `yaml:"targets,omitempty"`
+}
+
+// DiscordConfig - настройки Discord webhook
+type DiscordConfig struct {
+	Enabled    bool                     `yaml:"enabled,omitempty"`
+	WebhookURL string                   `yaml:"webhook_url"`
+	Username   string                   `yaml:"username,omitempty"`
+	Template   string                   `yaml:"template,omitempty"`
+	Targets    map[string]WebhookTarget `yaml:"targets,omitempty"` // channel не используется
+}
+
+// MatrixConfig - настройки Matrix client-server API
+type MatrixConfig struct {
+	Enabled       bool   `yaml:"enabled,omitempty"`
+	HomeserverURL string `yaml:"homeserver_url"`
+	AccessToken   string `yaml:"access_token"`
+	RoomID        string `yaml:"room_id"`
+	Template      string `yaml:"template,omitempty"` // HTML для formatted_body
+	// Targets - именованные комнаты: имя -> room_id
+	Targets map[string]string `yaml:"targets,omitempty"`
+}
+
+// NtfyConfig - настройки публикации в ntfy (подходит и для UnifiedPush через ntfy)
+type NtfyConfig struct {
+	Enabled   bool   `yaml:"enabled,omitempty"`
+	ServerURL string `yaml:"server_url"`
+	Topic     string `yaml:"topic"`
+	Token     string `yaml:"token,omitempty"`
+	Template  string `yaml:"template,omitempty"`
+	// Targets - именованные топики: имя -> topic
+	Targets map[string]string `yaml:"targets,omitempty"`
+}
+
+// GotifyConfig - настройки публикации в Gotify
+type GotifyConfig struct {
+	Enabled   bool   `yaml:"enabled,omitempty"`
+	ServerURL string `yaml:"server_url"`
+	Token     string `yaml:"token"` // токен приложения
+	Template  string `yaml:"template,omitempty"`
+	// Targets - именованные приложения: имя -> токен приложения
+	Targets map[string]string `yaml:"targets,omitempty"`
+}
+
+// DesktopConfig - настройки всплывающих уведомлений на рабочем столе (D-Bus)
+type DesktopConfig struct {
+	Enabled        bool   `yaml:"enabled,omitempty"`
+	AppName        string `yaml:"app_name,omitempty"`
+	TimeoutSeconds int    `yaml:"timeout_seconds,omitempty"`
+	Template       string `yaml:"template,omitempty"`
 }
 
 // IMAPConfig - настройки почтового сервера
@@ -42,6 +207,104 @@ type MonitoringConfig struct {
 	CheckIntervalSeconds int `yaml:"check_interval_seconds,omitempty"`
 	MaxEmails            int `yaml:"max_emails,omitempty"`
 	RetryAttempts        int `yaml:"retry_attempts,omitempty"`
+	// Explain - писать в лог разбор каждого письма всеми правилами
+	Explain bool `yaml:"explain,omitempty"`
+	// StatsIntervalMinutes - как часто писать статистику в лог, 0 - не писать (например, есть /metrics)
+	StatsIntervalMinutes int `yaml:"stats_interval_minutes,omitempty"`
+}
+
+// OutboxConfig - настройки очереди доставки уведомлений
+type OutboxConfig struct {
+	Path                  string `yaml:"path,omitempty"`
+	MaxAttempts           int    `yaml:"max_attempts,omitempty"`
+	InitialBackoffSeconds int    `yaml:"initial_backoff_seconds,omitempty"`
+	MaxBackoffSeconds     int    `yaml:"max_backoff_seconds,omitempty"`
+	// DeadRetentionDays и MaxDead ограничивают недоставленные уведомления (dead),
+	// которые хранятся для разбора. 0 - без ограничения
+	DeadRetentionDays int `yaml:"dead_retention_days,omitempty"`
+	MaxDead           int `yaml:"max_dead,omitempty"`
+}
+
+// DigestConfig - дайджест малозначимых писем: почти сработавшие правила и алерты
+// уровня max_level и ниже копятся и уходят одной сводкой по расписанию
+type DigestConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	Path          string            `yaml:"path,omitempty"`
+	NearMissRatio float64           `yaml:"near_miss_ratio,omitempty"` // доля MinScore, 0 - не собирать
+	MaxLevel      models.AlertLevel `yaml:"max_level,omitempty"`       // по умолчанию low
+	IntervalHours int               `yaml:"interval_hours,omitempty"`
+	Times         []string          `yaml:"times,omitempty"` // "09:00", вместо interval_hours
+	Timezone      string            `yaml:"timezone,omitempty"`
+	// Actions - куда отправлять дайджест. Пусто - в действия правил
+	Actions []models.Action `yaml:"actions,omitempty"`
+}
+
+// GetMaxLevel возвращает максимальный уровень алерта, который идёт в дайджест
+func (d *DigestConfig) GetMaxLevel() models.AlertLevel {
+	if d.MaxLevel == 0 {
+		return models.AlertLow
+	}
+	return d.MaxLevel
+}
+
+// GetInterval возвращает период отправки дайджеста
+func (d *DigestConfig) GetInterval() time.Duration {
+	return time.Duration(d.IntervalHours) * time.Hour
+}
+
+// GetLocation возвращает часовой пояс расписания дайджеста
+func (d *DigestConfig) GetLocation() (*time.Location, error) {
+	if d.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(d.Timezone)
+}
+
+// DedupMode - что делать с повторным алертом
+type DedupMode string
+
+const (
+	DedupSuppress DedupMode = "suppress" // не отправлять
+	DedupUpdate   DedupMode = "update"   // обновить прежнее сообщение (Telegram), остальным отправить заново
+)
+
+// DedupModes - все режимы подавления повторов
+var DedupModes = []DedupMode{DedupSuppress, DedupUpdate}
+
+// DedupConfig - подавление повторов: одно и то же письмо, ответы в той же цепочке
+// (In-Reply-To/References) и письма с той же темой без Re:/Fwd:/Напоминание:
+type DedupConfig struct {
+	Enabled     bool      `yaml:"enabled"`
+	Path        string    `yaml:"path,omitempty"`
+	WindowHours int       `yaml:"window_hours,omitempty"` // отсчитывается от последнего повтора
+	Mode        DedupMode `yaml:"mode,omitempty"`
+}
+
+// GetWindow возвращает окно, в котором письма считаются повторами
+func (d *DedupConfig) GetWindow() time.Duration {
+	return time.Duration(d.WindowHours) * time.Hour
+}
+
+// HistoryConfig - журнал обработанных писем, алертов и попыток доставки
+// во встроенной базе bbolt. Из писем сохраняются только метаданные
+type HistoryConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Path          string `yaml:"path,omitempty"`
+	RetentionDays int    `yaml:"retention_days,omitempty"` // 0 - хранить всё
+}
+
+// GetRetention возвращает срок хранения записей, 0 - без ограничения
+func (h *HistoryConfig) GetRetention() time.Duration {
+	return time.Duration(h.RetentionDays) * 24 * time.Hour
+}
+
+// APIConfig - встроенный HTTP сервер состояния и управления.
+// POST-запросы требуют заголовок "Authorization: Bearer <token>",
+// без token управление выключено и доступно только чтение
+type APIConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Listen  string `yaml:"listen,omitempty"`
+	Token   string `yaml:"token,omitempty"`
 }
 
 func DefaultConfig() *Config {
@@ -57,6 +320,7 @@ func DefaultConfig() *Config {
 			CheckIntervalSeconds: 30,
 			MaxEmails:            20,
 			RetryAttempts:        3,
+			StatsIntervalMinutes: 5,
 		},
 		Notifiers: NotifiersConfig{
 			Telegram: &TelegramConfig{
@@ -65,9 +329,162 @@ func DefaultConfig() *Config {
 				ChatID:   0,
 			},
 		},
+		Outbox: OutboxConfig{
+			Path:                  "data/outbox.json",
+			MaxAttempts:           10,
+			InitialBackoffSeconds: 5,
+			MaxBackoffSeconds:     3600,
+			DeadRetentionDays:     7,
+			MaxDead:               1000,
+		},
+		Digest: DigestConfig{
+			Path:          "data/digest.json",
+			NearMissRatio: 0.5,
+			IntervalHours: 24,
+		},
+		Dedup: DedupConfig{
+			Path:        "data/dedup.json",
+			WindowHours: 72,
+			Mode:        DedupSuppress,
+		},
+		History: HistoryConfig{
+			Path:          "data/history.db",
+			RetentionDays: 90,
+		},
+		API: APIConfig{
+			Listen: "127.0.0.1:8080",
+		},
 	}
 }
 
+// QuietHoursConfig - тихие часы. В это время сразу уходят только алерты
+// уровня min_level и выше, остальные копятся и отправляются утренней сводкой
+type QuietHoursConfig struct {
+	Enabled  bool              `yaml:"enabled"`
+	Timezone string            `yaml:"timezone,omitempty"`  // например Europe/Moscow, по умолчанию локальный
+	MinLevel models.AlertLevel `yaml:"min_level,omitempty"` // по умолчанию high
+	Schedule []QuietWindow     `yaml:"schedule"`
+}
+
+// QuietWindow - интервал тихих часов. Если to раньше from, интервал
+// переходит через полночь и относится к дню начала
+type QuietWindow struct {
+	Days []string `yaml:"days,omitempty"` // mon..sun, пусто - каждый день
+	From string   `yaml:"from"`           // "23:00"
+	To   string   `yaml:"to"`             // "07:00"
+}
+
+// GetLocation возвращает часовой пояс тихих часов
+func (q *QuietHoursConfig) GetLocation() (*time.Location, error) {
+	if q.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(q.Timezone)
+}
+
+// GetMinLevel возвращает минимальный уровень для доставки в тихие часы
+func (q *QuietHoursConfig) GetMinLevel() models.AlertLevel {
+	if q.MinLevel == 0 {
+		return models.AlertHigh
+	}
+	return q.MinLevel
+}
+
+// ParseClock разбирает время суток "HH:MM" в минуты от полуночи
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s', expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseWeekday разбирает день недели: mon, tue, ... или полное английское название
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(s)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if s == name || s == name[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday '%s'", s)
+}
+
+// IsEnabled проверяет, что блок нотификатора actionType описан и включён
+func (n *NotifiersConfig) IsEnabled(actionType models.ActionType) bool {
+	switch actionType {
+	case models.ActionNotifyTelegram:
+		return n.Telegram != nil && n.Telegram.Enabled
+	case models.ActionNotifySlack:
+		return n.Slack != nil && n.Slack.Enabled
+	case models.ActionNotifyMattermost:
+		return n.Mattermost != nil && n.Mattermost.Enabled
+	case models.ActionNotifyDiscord:
+		return n.Discord != nil && n.Discord.Enabled
+	case models.ActionNotifyMatrix:
+		return n.Matrix != nil && n.Matrix.Enabled
+	case models.ActionNotifyNtfy:
+		return n.Ntfy != nil && n.Ntfy.Enabled
+	case models.ActionNotifyGotify:
+		return n.Gotify != nil && n.Gotify.Enabled
+	case models.ActionNotifyDesktop:
+		return n.Desktop != nil && n.Desktop.Enabled
+	}
+	return false
+}
+
+// HasTarget проверяет, что у нотификатора actionType описан получатель target
+func (n *NotifiersConfig) HasTarget(actionType models.ActionType, target string) bool {
+	var ok bool
+	switch actionType {
+	case models.ActionNotifyTelegram:
+		if n.Telegram != nil {
+			_, ok = n.Telegram.Targets[target]
+		}
+	case models.ActionNotifySlack:
+		if n.Slack != nil {
+			_, ok = n.Slack.Targets[target]
+		}
+	case models.ActionNotifyMattermost:
+		if n.Mattermost != nil {
+			_, ok = n.Mattermost.Targets[target]
+		}
+	case models.ActionNotifyDiscord:
+		if n.Discord != nil {
+			_, ok = n.Discord.Targets[target]
+		}
+	case models.ActionNotifyMatrix:
+		if n.Matrix != nil {
+			_, ok = n.Matrix.Targets[target]
+		}
+	case models.ActionNotifyNtfy:
+		if n.Ntfy != nil {
+			_, ok = n.Ntfy.Targets[target]
+		}
+	case models.ActionNotifyGotify:
+		if n.Gotify != nil {
+			_, ok = n.Gotify.Targets[target]
+		}
+	}
+	return ok
+}
+
+// GetInitialBackoff возвращает задержку перед первым повтором
+func (o *OutboxConfig) GetInitialBackoff() time.Duration {
+	return time.Duration(o.InitialBackoffSeconds) * time.Second
+}
+
+// GetDeadRetention возвращает, сколько хранить недоставленные уведомления, 0 - бессрочно
+func (o *OutboxConfig) GetDeadRetention() time.Duration {
+	return time.Duration(o.DeadRetentionDays) * 24 * time.Hour
+}
+
+// GetMaxBackoff возвращает максимальную задержку между повторами
+func (o *OutboxConfig) GetMaxBackoff() time.Duration {
+	return time.Duration(o.MaxBackoffSeconds) * time.Second
+}
+
 // Вспомогательный метод для получения duration
 func (m *MonitoringConfig) GetCheckInterval() time.Duration {
 	return time.Duration(m.CheckIntervalSeconds) * time.Second
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// EnvPrefix - префикс переменных окружения, переопределяющих настройки:
+// imap.password задаётся через CATCHLETTER_IMAP_PASSWORD
+const EnvPrefix = "CATCHLETTER"
+
+// fileSuffix - суффикс настроек и переменных, значение которых читается из файла:
+// password_file в YAML, CATCHLETTER_IMAP_PASSWORD_FILE в окружении
+const fileSuffix = "_file"
+
+// EnvVar - переменная окружения для одной настройки
+type EnvVar struct {
+	Name string `json:"name"` // CATCHLETTER_IMAP_PASSWORD
+	Path string `json:"path"` // imap.password
+	Type string `json:"type"` // string, int, bool, float64...
+}
+
+// EnvVars возвращает переменные окружения для всех скалярных настроек в порядке полей конфига.
+// Списки и словари (rules, targets, include) через окружение не задаются
+func EnvVars() []EnvVar {
+	var vars []EnvVar
+	var walk func(t reflect.Type, path []string)
+	walk = func(t reflect.Type, path []string) {
+		for _, field := range yamlFields(t) {
+			fieldPath := append(path[:len(path):len(path)], field.name)
+			switch {
+			case isScalar(field.typ):
+				vars = append(vars, EnvVar{
+					Name: envName(fieldPath),
+					Path: strings.Join(fieldPath, "."),
+					Type: typeName(field.typ),
+				})
+			case isStruct(field.typ):
+				walk(deref(field.typ), fieldPath)
+			}
+		}
+	}
+	walk(reflect.TypeFor[Config](), nil)
+	return vars
+}
+
+// applyEnv задаёт настройки из переменных окружения поверх значений из файла.
+// Блоки нотификаторов, которых нет в файле, создаются, если задана хотя бы одна их переменная
+func applyEnv(cfg *Config) error {
+	_, err := applyEnvValue(reflect.ValueOf(cfg).Elem(), nil)
+	return err
+}
+
+// applyEnvValue обходит поля структуры v и сообщает, была ли задана хоть одна настройка
+func applyEnvValue(v reflect.Value, path []string) (bool, error) {
+	var applied bool
+	for _, field := range yamlFields(v.Type()) {
+		fieldPath := append(path[:len(path):len(path)], field.name)
+		value := v.Field(field.index)
+
+		switch {
+		case isScalar(field.typ):
+			ok, err := applyEnvScalar(value, envName(fieldPath))
+			if err != nil {
+				return false, err
+			}
+			applied = applied || ok
+
+		case field.typ.Kind() == reflect.Struct:
+			ok, err := applyEnvValue(value, fieldPath)
+			if err != nil {
+				return false, err
+			}
+			applied = applied || ok
+
+		case isStruct(field.typ):
+			// Указатель на блок: создаём его, только если что-то из него задано
+			target := value
+			if value.IsNil() {
+				target = reflect.New(field.typ.Elem())
+			}
+			ok, err := applyEnvValue(target.Elem(), fieldPath)
+			if err != nil {
+				return false, err
+			}
+			if ok && value.IsNil() {
+				value.Set(target)
+			}
+			applied = applied || ok
+		}
+	}
+	return applied, nil
+}
+
+// applyEnvScalar задаёт значение из переменной name или, для строк, из файла из name_FILE
+func applyEnvScalar(value reflect.Value, name string) (bool, error) {
+	raw, ok := os.LookupEnv(name)
+	if fileName := name + strings.ToUpper(fileSuffix); value.Kind() == reflect.String {
+		if path, fileOk := os.LookupEnv(fileName); fileOk {
+			if ok {
+				return false, fmt.Errorf("environment variables %s and %s are mutually exclusive", name, fileName)
+			}
+			content, err := readSecretFile(path)
+			if err != nil {
+				return false, fmt.Errorf("environment variable %s: %w", fileName, err)
+			}
+			raw, ok = content, true
+		}
+	}
+	if !ok {
+		return false, nil
+	}
+
+	// Разбираем как YAML-скаляр: так работают и числа, и уровни вида "high"
+	node := yaml.Node{Kind: yaml.ScalarNode, Value: raw}
+	if value.Kind() == reflect.String {
+		node.Tag = "!!str"
+	}
+	if err := node.Decode(value.Addr().Interface()); err != nil {
+		return false, fmt.Errorf("environment variable %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// envPattern - ${VAR} или ${VAR:-значение по умолчанию}, $$ - сам знак $
+var envPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandNode подставляет в значения переменные окружения ${VAR} и заменяет ключи вида
+// password_file на password с содержимым файла. t - тип, в который будет разобран узел:
+// по нему видно, у каких строковых настроек может быть вариант _file
+func expandNode(path string, node *yaml.Node, t reflect.Type) error {
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			if err := expandNode(path, child, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		return expandScalar(path, node)
+	}
+
+	t = deref(t)
+	switch {
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := make(map[string]yamlField)
+		for _, field := range yamlFields(t) {
+			fields[field.name] = field
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if field, ok := fields[key.Value]; ok {
+				if err := expandNode(path, value, field.typ); err != nil {
+					return err
+				}
+				continue
+			}
+
+			name, ok := strings.CutSuffix(key.Value, fileSuffix)
+			field, known := fields[name]
+			if !ok || !known || field.typ.Kind() != reflect.String {
+				continue
+			}
+			if mappingValue(node, name) != nil {
+				return fmt.Errorf("%s:%d: %s and %s are mutually exclusive", path, key.Line, name, key.Value)
+			}
+			if err := expandScalar(path, value); err != nil {
+				return err
+			}
+			content, err := readSecretFile(resolvePath(path, value.Value))
+			if err != nil {
+				return fmt.Errorf("%s:%d: %s: %w", path, key.Line, key.Value, err)
+			}
+			key.Value = name
+			*value = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: content, Line: value.Line, Column: value.Column}
+		}
+		return nil
+
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := expandNode(path, node.Content[i], t.Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case node.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for _, item := range node.Content {
+			if err := expandNode(path, item, t.Elem()); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		// Тип не совпал с узлом - ошибку покажет разбор, здесь только подставляем переменные
+		for _, child := range node.Content {
+			if err := expandNode(path, child, reflect.TypeFor[any]()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// expandScalar подставляет переменные окружения в значение узла
+func expandScalar(path string, node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode || !strings.Contains(node.Value, "$") {
+		return nil
+	}
+
+	var missing string
+	expanded := envPattern.ReplaceAllStringFunc(node.Value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		groups := envPattern.FindStringSubmatch(match)
+		value, ok := os.LookupEnv(groups[1])
+		if groups[2] != "" && value == "" {
+			return groups[3]
+		}
+		if !ok && missing == "" {
+			missing = groups[1]
+		}
+		return value
+	})
+	if missing != "" {
+		return fmt.Errorf("%s:%d: environment variable %s is not set", path, node.Line, missing)
+	}
+
+	if expanded != node.Value {
+		node.Value = expanded
+		// Без кавычек тип определяется заново: port: ${IMAP_PORT} - число
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+	return nil
+}
+
+// readSecretFile читает секрет из файла без завершающего перевода строки
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// yamlField - поле структуры конфига с его именем в YAML
+type yamlField struct {
+	name  string
+	index int
+	typ   reflect.Type
+}
+
+// yamlFields возвращает экспортируемые поля структуры, которые есть в YAML
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, yamlField{name: name, index: i, typ: field.Type})
+	}
+	return fields
+}
+
+// typeName - тип настройки для документации. Типы со своим разбором (уровни важности) задаются строкой
+func typeName(t reflect.Type) string {
+	if reflect.PointerTo(t).Implements(reflect.TypeFor[yaml.Unmarshaler]()) {
+		return "string"
+	}
+	return t.Kind().String()
+}
+
+func envName(path []string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.Join(path, "_"))
+}
+
+func deref(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+func isStruct(t reflect.Type) bool {
+	return deref(t).Kind() == reflect.Struct
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
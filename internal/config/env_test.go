@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const envConfig = `imap:
+  server: ${IMAP_SERVER:-imap.example.com}
+  port: ${IMAP_PORT}
+  username: "${IMAP_USER}"
+  password_file: secrets/imap
+rules:
+  - id: "rule-1"
+    name: "Цена $$100"
+    enabled: true
+    min_score: 50
+    conditions:
+      - {type: subject, operator: contains, value: "${KEYWORD}", weight: 60}
+    actions: ["telegram"]
+`
+
+func writeEnvConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "secrets"), 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secrets", "imap"), []byte("s3cret\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestLoadInterpolation(t *testing.T) {
+	t.Setenv("IMAP_PORT", "1993")
+	t.Setenv("IMAP_USER", "007")
+	t.Setenv("KEYWORD", "медосмотр")
+	path := writeEnvConfig(t, envConfig)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.IMAP.Server != "imap.example.com" || cfg.IMAP.Port != 1993 || cfg.IMAP.Username != "007" {
+		t.Errorf("incorrect imap config: %+v", cfg.IMAP)
+	}
+	if cfg.IMAP.Password != "s3cret" {
+		t.Errorf("expected password from file, got: '%s'", cfg.IMAP.Password)
+	}
+	if cfg.Rules[0].Name != "Цена $100" || cfg.Rules[0].Conditions[0].Value != "медосмотр" {
+		t.Errorf("incorrect rule: %+v", cfg.Rules[0])
+	}
+}
+
+func TestLoadInterpolationErrors(t *testing.T) {
+	t.Setenv("IMAP_PORT", "993")
+	t.Setenv("IMAP_USER", "user")
+
+	path := writeEnvConfig(t, envConfig)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "config.yaml:12: environment variable KEYWORD is not set") {
+		t.Errorf("expected missing variable error, got: %v", err)
+	}
+
+	t.Setenv("KEYWORD", "медосмотр")
+	path = writeEnvConfig(t, strings.Replace(envConfig, "password_file:", "password: x\n  password_file:", 1))
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "password and password_file are mutually exclusive") {
+		t.Errorf("expected mutually exclusive error, got: %v", err)
+	}
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	t.Setenv("IMAP_PORT", "993")
+	t.Setenv("IMAP_USER", "user")
+	t.Setenv("KEYWORD", "медосмотр")
+	path := writeEnvConfig(t, envConfig)
+
+	t.Setenv("CATCHLETTER_IMAP_SERVER", "mail.hse.ru")
+	t.Setenv("CATCHLETTER_MONITORING_EXPLAIN", "true")
+	t.Setenv("CATCHLETTER_DIGEST_MAX_LEVEL", "medium")
+	t.Setenv("CATCHLETTER_NOTIFIERS_TELEGRAM_ENABLED", "true")
+	t.Setenv("CATCHLETTER_NOTIFIERS_TELEGRAM_BOT_TOKEN_FILE", filepath.Join(filepath.Dir(path), "secrets", "imap"))
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.IMAP.Server != "mail.hse.ru" || !cfg.Monitoring.Explain || cfg.Digest.MaxLevel.String() != "medium" {
+		t.Errorf("overrides not applied: %+v %+v %+v", cfg.IMAP, cfg.Monitoring, cfg.Digest)
+	}
+	if cfg.Notifiers.Telegram == nil || !cfg.Notifiers.Telegram.Enabled || cfg.Notifiers.Telegram.BotToken != "s3cret" {
+		t.Errorf("expected telegram block from env, got: %+v", cfg.Notifiers.Telegram)
+	}
+	if cfg.Notifiers.Slack != nil {
+		t.Error("slack block must not be created without its variables")
+	}
+
+	t.Setenv("CATCHLETTER_IMAP_PORT", "abc")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "CATCHLETTER_IMAP_PORT") {
+		t.Errorf("expected invalid variable error, got: %v", err)
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	vars := make(map[string]EnvVar)
+	for _, v := range EnvVars() {
+		vars[v.Name] = v
+	}
+
+	for name, path := range map[string]string{
+		"CATCHLETTER_IMAP_PASSWORD":                "imap.password",
+		"CATCHLETTER_NOTIFIERS_TELEGRAM_BOT_TOKEN": "notifiers.telegram.bot_token",
+		"CATCHLETTER_SCORING_LEVELS_HIGH":          "scoring.levels.high",
+		"CATCHLETTER_API_TOKEN":                    "api.token",
+	} {
+		if vars[name].Path != path {
+			t.Errorf("expected %s for %s, got: %+v", path, name, vars[name])
+		}
+	}
+	if _, ok := vars["CATCHLETTER_RULES"]; ok {
+		t.Error("rules must not be configurable from environment")
+	}
+	if vars["CATCHLETTER_DIGEST_MAX_LEVEL"].Type != "string" {
+		t.Errorf("expected level as string, got: %+v", vars["CATCHLETTER_DIGEST_MAX_LEVEL"])
+	}
+}
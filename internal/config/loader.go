@@ -3,13 +3,19 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
 	"go.yaml.in/yaml/v3"
 )
 
 type FileManager interface {
 	ReadFile(path string) ([]byte, error)
 	CheckFile(path string) bool
+	Glob(pattern string) ([]string, error)
 }
 
 type OSFileManager struct{}
@@ -23,6 +29,10 @@ func (m OSFileManager) CheckFile(path string) bool {
 	return err == nil
 }
 
+func (m OSFileManager) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
 // Load загружает конфигурацию из файла
 func Load(configPath string) (*Config, error) {
 	return loadWithFileManager(configPath, OSFileManager{})
@@ -43,21 +53,223 @@ func loadWithFileManager(configPath string, m FileManager) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// ${VAR} и ключи вида password_file раскрываются до разбора в структуру
+	doc, err := readYAML(configPath, data, reflect.TypeFor[Config]())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
 	// Создаем конфиг с значениями по умолчанию
 	cfg := DefaultConfig()
 
-	if err := yaml.Unmarshal(data, cfg); err != nil {
+	if err := doc.Decode(cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	// Правила основного конфига и подключённых файлов собираются в один список,
+	// для каждой настройки запоминаем файл, строку и столбец, чтобы ошибки указывали на место
+	cfg.positions = make(map[string]Position)
+	if len(doc.Content) > 0 {
+		recordPositions(cfg.positions, configPath, "", doc.Content[0])
+	}
+
+	files, err := includedFiles(configPath, cfg, m)
+	if err != nil {
+		return nil, err
+	}
+	cfg.IncludedFiles = files
+	for i := range cfg.Rules {
+		cfg.RuleSources = append(cfg.RuleSources, RuleSource{File: configPath, Index: i})
+	}
+	for _, file := range files {
+		data, err := m.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules file: %w", err)
+		}
+		rules, err := parseRulesFile(file, data, len(cfg.Rules), cfg.positions)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rules {
+			cfg.RuleSources = append(cfg.RuleSources, RuleSource{File: file, Index: i})
+		}
+		cfg.Rules = append(cfg.Rules, rules...)
+	}
+
+	// Переменные CATCHLETTER_* главнее файла: так секреты не обязаны лежать в конфиге
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
 	// Валидация
 	if err := Validate(cfg); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
 
+	cfg.Path = configPath
 	return cfg, nil
 }
 
+// LoadRules загружает правила из отдельного файла с блоком rules, как в основном конфиге.
+// scoring нужен для проверки min_score так же, как в основном конфиге
+func LoadRules(path string, scoring *ScoringConfig) ([]*models.Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	positions := make(map[string]Position)
+	rules, err := parseRulesFile(path, data, 0, positions)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &validator{positions: positions}
+	v.rules(rules, scoring, nil)
+	if err := v.err(); err != nil {
+		return nil, fmt.Errorf("rules validation failed: %w", err)
+	}
+	return rules, nil
+}
+
+// rulesFile - файл только с правилами, подключаемый через include или rules_dir
+type rulesFile struct {
+	Rules []*models.Rule `yaml:"rules"`
+}
+
+// readYAML разбирает файл в дерево узлов и раскрывает в нём переменные окружения
+// и ключи с суффиксом _file. t - тип, в который потом будет разобрано дерево
+func readYAML(path string, data []byte, t reflect.Type) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if err := expandNode(path, &doc, t); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// parseRulesFile читает файл с блоком rules. offset - сколько правил собрано до этого файла:
+// места правил записываются в positions под их индексами в общем списке
+func parseRulesFile(path string, data []byte, offset int, positions map[string]Position) ([]*models.Rule, error) {
+	doc, err := readYAML(path, data, reflect.TypeFor[rulesFile]())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	node := mappingValue(doc.Content[0], "rules")
+	if node == nil {
+		return nil, nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s:%d: rules must be a list", path, node.Line)
+	}
+
+	rules := make([]*models.Rule, 0, len(node.Content))
+	for i, item := range node.Content {
+		var rule *models.Rule
+		if err := item.Decode(&rule); err != nil {
+			return nil, fmt.Errorf("%s:%d: failed to parse rule: %w", path, item.Line, err)
+		}
+		rules = append(rules, rule)
+		recordPositions(positions, path, fmt.Sprintf("rules[%d]", offset+i), item)
+	}
+	return rules, nil
+}
+
+// recordPositions запоминает место каждой настройки дерева node под путём prefix.
+// Для скаляров это само значение, для блоков и списков - их ключ или первый элемент
+func recordPositions(positions map[string]Position, file, prefix string, node *yaml.Node) {
+	if prefix != "" {
+		if _, ok := positions[prefix]; !ok {
+			positions[prefix] = Position{File: file, Line: node.Line, Column: node.Column}
+		}
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			path := key.Value
+			if prefix != "" {
+				path = prefix + "." + key.Value
+			}
+			// Место блока - его ключ: строка "imap:", а не первая настройка внутри
+			if value.Kind != yaml.ScalarNode {
+				positions[path] = Position{File: file, Line: key.Line, Column: key.Column}
+			}
+			recordPositions(positions, file, path, value)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			recordPositions(positions, file, fmt.Sprintf("%s[%d]", prefix, i), item)
+		}
+	case yaml.AliasNode:
+		recordPositions(positions, file, prefix, node.Alias)
+	}
+}
+
+// includedFiles возвращает файлы из include и rules_dir без повторов, в порядке подключения
+func includedFiles(configPath string, cfg *Config, m FileManager) ([]string, error) {
+	var files []string
+	add := func(patterns ...string) error {
+		var matches []string
+		for _, pattern := range patterns {
+			found, err := m.Glob(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+			}
+			matches = append(matches, found...)
+		}
+		slices.Sort(matches)
+		for _, match := range matches {
+			if filepath.Clean(match) != filepath.Clean(configPath) && !slices.Contains(files, match) {
+				files = append(files, match)
+			}
+		}
+		return nil
+	}
+
+	for _, include := range cfg.Include {
+		pattern := resolvePath(configPath, include)
+		before := len(files)
+		if err := add(pattern); err != nil {
+			return nil, err
+		}
+		// Файл без шаблона должен существовать, иначе правила молча пропадут
+		if len(files) == before && !hasGlobMeta(include) {
+			return nil, fmt.Errorf("included file not found: %s", pattern)
+		}
+	}
+
+	if cfg.RulesDir != "" {
+		dir := resolvePath(configPath, cfg.RulesDir)
+		if !m.CheckFile(dir) {
+			return nil, fmt.Errorf("rules_dir not found: %s", dir)
+		}
+		if err := add(filepath.Join(dir, "*.yaml"), filepath.Join(dir, "*.yml")); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// resolvePath считает относительный путь от каталога основного конфига
+func resolvePath(configPath, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(configPath), path)
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
 // findConfigPath ищет конфиг в стандартных местах
 func findConfigPath(m FileManager) string {
 	possiblePaths := []string{
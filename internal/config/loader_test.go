@@ -2,6 +2,10 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -20,6 +24,10 @@ func (m MockFileManager) CheckFile(path string) bool {
 	return path == "./config.yaml"
 }
 
+func (m MockFileManager) Glob(pattern string) ([]string, error) {
+	return nil, nil
+}
+
 func TestLoad(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -48,3 +56,154 @@ func TestLoad(t *testing.T) {
 		})
 	}
 }
+
+const includeConfig = `imap:
+  server: imap.example.com
+  username: user
+  password: secret
+  port: 993
+include:
+  - "hr.yaml"
+rules_dir: "rules.d"
+rules:
+  - id: "rule-1"
+    name: "Медосмотр"
+    enabled: true
+    min_score: 50
+    conditions:
+      - {type: subject, operator: contains, value: "медосмотр", weight: 60}
+    actions: ["telegram"]
+`
+
+const includeRule = `rules:
+  - id: "%s"
+    name: "%s"
+    enabled: true
+    min_score: 50
+    conditions:
+      - {type: subject, operator: contains, value: "%s", weight: 60}
+    actions: ["telegram"]
+`
+
+func writeIncludeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "rules.d"), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return filepath.Join(dir, "config.yaml")
+}
+
+func TestLoadIncludes(t *testing.T) {
+	path := writeIncludeFiles(t, map[string]string{
+		"config.yaml":        includeConfig,
+		"hr.yaml":            fmt.Sprintf(includeRule, "rule-2", "Отпуск", "отпуск"),
+		"rules.d/b.yaml":     fmt.Sprintf(includeRule, "rule-4", "Стипендия", "стипендия"),
+		"rules.d/a.yml":      fmt.Sprintf(includeRule, "rule-3", "Дедлайн", "дедлайн"),
+		"rules.d/readme.txt": "не правила",
+	})
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, rule := range cfg.Rules {
+		names = append(names, rule.Name)
+	}
+	if got := strings.Join(names, ","); got != "Медосмотр,Отпуск,Дедлайн,Стипендия" {
+		t.Errorf("incorrect rules order: %s", got)
+	}
+
+	dir := filepath.Dir(path)
+	if got := cfg.RuleSource(0); got != (RuleSource{File: path, Index: 0}) {
+		t.Errorf("expected main config for rule-1, got: %+v", got)
+	}
+	if got := cfg.RuleSource(2); got != (RuleSource{File: filepath.Join(dir, "rules.d", "a.yml"), Index: 0}) {
+		t.Errorf("incorrect source for rule-3: %+v", got)
+	}
+}
+
+func TestLoadIncludesDuplicates(t *testing.T) {
+	tests := []struct {
+		name     string
+		included string
+		expected string
+	}{
+		{
+			name:     "Повтор ID",
+			included: fmt.Sprintf(includeRule, "rule-1", "Отпуск", "отпуск"),
+			expected: "hr.yaml:2:9: rules[1].id: duplicate rule id: rule-1 (first defined at ",
+		},
+		{
+			name:     "Повтор имени",
+			included: fmt.Sprintf(includeRule, "rule-2", "Медосмотр", "отпуск"),
+			expected: "hr.yaml:3:11: rules[1].name: duplicate rule name: Медосмотр",
+		},
+		{
+			name:     "Ошибка в правиле",
+			included: strings.Replace(fmt.Sprintf(includeRule, "rule-2", "Отпуск", "отпуск"), "min_score: 50", "min_score: -5", 1),
+			expected: "hr.yaml:5:16: rules[1].min_score: min_score cannot be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeIncludeFiles(t, map[string]string{"config.yaml": includeConfig, "hr.yaml": tt.included})
+
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error with '%s', got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadMissingInclude(t *testing.T) {
+	path := writeIncludeFiles(t, map[string]string{"config.yaml": includeConfig})
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "included file not found") {
+		t.Errorf("expected missing include error, got: %v", err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	config := strings.Replace(includeConfig, `  - "hr.yaml"`, `  - "hr.yaml"
+  - "empty.yaml"`, 1)
+	path := writeIncludeFiles(t, map[string]string{
+		"config.yaml": config,
+		// Правило без id и файл без правил тоже нужно отслеживать
+		"hr.yaml": `rules:
+  - name: "Отпуск"
+    enabled: true
+    min_score: 50
+    conditions:
+      - {type: subject, operator: contains, value: "отпуск", weight: 60}
+    actions: ["telegram"]
+`,
+		"empty.yaml":     "rules: []\n",
+		"rules.d/a.yaml": fmt.Sprintf(includeRule, "rule-3", "Дедлайн", "дедлайн"),
+	})
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dir := filepath.Dir(path)
+	expected := []string{
+		path,
+		filepath.Join(dir, "hr.yaml"),
+		filepath.Join(dir, "empty.yaml"),
+		filepath.Join(dir, "rules.d", "a.yaml"),
+		filepath.Join(dir, "rules.d"),
+	}
+	if got := cfg.Files(); !slices.Equal(got, expected) {
+		t.Errorf("incorrect files, expected: %v, got: %v", expected, got)
+	}
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// schemaDraft - версия JSON Schema, которую понимают yaml-language-server и большинство редакторов
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaRequired - обязательные поля, без которых объект не имеет смысла.
+// Остальное проверяет Validate: многие настройки имеют значения по умолчанию или задаются окружением
+var schemaRequired = map[reflect.Type][]string{
+	reflect.TypeFor[models.Rule]():      {"name", "conditions", "actions"},
+	reflect.TypeFor[models.Condition](): {"type", "operator", "value", "weight"},
+}
+
+// Schema возвращает JSON Schema файла конфига. Подходит и для файлов из include и rules_dir:
+// в них только блок rules. Схема строится по тем же структурам и спискам допустимых значений
+// (типы условий, операторы, типы действий), что разбор и Validate, поэтому не расходится с ними
+func Schema() ([]byte, error) {
+	g := &schemaGenerator{defs: make(map[string]map[string]any)}
+	root := g.object(reflect.TypeFor[Config]())
+	root["$schema"] = schemaDraft
+	root["title"] = "CatchAnImportantLetter config"
+	root["$defs"] = g.defs
+
+	// Для header нужно имя заголовка
+	g.defs["Condition"]["if"] = map[string]any{
+		"properties": map[string]any{"type": map[string]any{"const": models.ConditionHeader}},
+		"required":   []string{"type"},
+	}
+	g.defs["Condition"]["then"] = map[string]any{"required": []string{"field"}}
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaGenerator строит схемы типов, структуры выносятся в $defs под именами Go-типов
+type schemaGenerator struct {
+	defs map[string]map[string]any
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	if values, ok := enumValues(t); ok {
+		return withEnv(map[string]any{"type": "string", "enum": values})
+	}
+
+	switch t {
+	case reflect.TypeFor[models.Action]():
+		return g.ref("Action", actionSchema)
+	case reflect.TypeFor[models.AlertLevel]():
+		return g.ref("AlertLevel", levelSchema)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Struct:
+		return g.ref(t.Name(), func() map[string]any { return g.object(t) })
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		schema := map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+		if values, ok := enumValues(t.Key()); ok {
+			schema["propertyNames"] = map[string]any{"enum": values}
+		}
+		return schema
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return withEnv(map[string]any{"type": "boolean"})
+	case reflect.Float32, reflect.Float64:
+		return withEnv(map[string]any{"type": "number"})
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return withEnv(map[string]any{"type": "integer"})
+	}
+	return map[string]any{}
+}
+
+// object - схема структуры по её YAML-полям. У строковых настроек блоков конфига
+// есть вариант с суффиксом _file (см. expandNode); в правилах он не предлагается,
+// чтобы не засорять подсказки редактора
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	for _, field := range yamlFields(t) {
+		properties[field.name] = g.schema(field.typ)
+		if field.typ.Kind() == reflect.String && t.PkgPath() == reflect.TypeFor[Config]().PkgPath() {
+			properties[field.name+fileSuffix] = map[string]any{
+				"type":        "string",
+				"description": fmt.Sprintf("file to read %s from", field.name),
+			}
+		}
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if required, ok := schemaRequired[t]; ok {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// ref выносит схему в $defs и возвращает ссылку на неё
+func (g *schemaGenerator) ref(name string, build func() map[string]any) map[string]any {
+	if _, ok := g.defs[name]; !ok {
+		g.defs[name] = build()
+	}
+	return map[string]any{"$ref": "#/$defs/" + name}
+}
+
+// enumValues возвращает допустимые значения строковых типов со списком значений
+func enumValues(t reflect.Type) ([]string, bool) {
+	switch t {
+	case reflect.TypeFor[models.ConditionType]():
+		return stringValues(models.ConditionTypes), true
+	case reflect.TypeFor[models.Operator]():
+		return stringValues(models.Operators), true
+	case reflect.TypeFor[models.ActionType]():
+		return stringValues(models.ActionTypes), true
+	case reflect.TypeFor[DedupMode]():
+		return stringValues(DedupModes), true
+	}
+	return nil, false
+}
+
+// actionSchema - действие строкой "тип" или "тип:получатель" или объектом (см. models.Action)
+func actionSchema() map[string]any {
+	types := stringValues(models.ActionTypes)
+	return map[string]any{
+		"anyOf": []any{
+			map[string]any{
+				"type":    "string",
+				"pattern": fmt.Sprintf("^\\s*(%s)\\s*(:.+)?$", strings.Join(types, "|")),
+			},
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"type":   map[string]any{"type": "string", "enum": types},
+					"target": map[string]any{"type": "string"},
+				},
+				"required":             []string{"type"},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+// levelSchema - уровень важности словом или числом, как в models.ParseAlertLevel
+func levelSchema() map[string]any {
+	var names []string
+	for level := models.AlertLow; level <= models.AlertCritical; level++ {
+		names = append(names, level.String())
+	}
+	return map[string]any{
+		"anyOf": []any{
+			map[string]any{"type": "string", "enum": names},
+			map[string]any{"type": "integer", "minimum": int(models.AlertLow), "maximum": int(models.AlertCritical)},
+		},
+	}
+}
+
+// withEnv разрешает вместо значения подстановку ${VAR}: её тип становится известен только при загрузке
+func withEnv(schema map[string]any) map[string]any {
+	return map[string]any{
+		"anyOf": []any{schema, map[string]any{"type": "string", "pattern": "\\$\\{"}},
+	}
+}
+
+func stringValues[T ~string](values []T) []string {
+	result := make([]string, len(values))
+	for i, value := range values {
+		result[i] = string(value)
+	}
+	return result
+}
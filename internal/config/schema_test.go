@@ -0,0 +1,126 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const schemaFile = "../../configs/config.schema.json"
+
+func TestSchemaUpToDate(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	published, err := os.ReadFile(schemaFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(schema, published) {
+		t.Errorf("%s is outdated, regenerate it: go run ./cmd/catchletter config schema > configs/config.schema.json", schemaFile)
+	}
+}
+
+// TestSchemaExample проверяет пример конфига по схеме: все ключи известны, значения из списков допустимы
+func TestSchemaExample(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var root map[string]any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile("../../configs/config.example.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := schemaChecker{defs: root["$defs"].(map[string]any)}
+	s.check(doc.Content[0], root, "")
+	for _, problem := range s.problems {
+		t.Error(problem)
+	}
+
+	// Ошибка в правиле видна по схеме
+	var bad yaml.Node
+	if err := yaml.Unmarshal([]byte("rules:\n  - name: x\n    conditions: [{type: to, operator: contains, value: x, weight: 1}]\n    actions: [email]\n    min_scor: 5\n"), &bad); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.problems = nil
+	s.check(bad.Content[0], root, "")
+	expected := []string{"rules[0].conditions[0].type", "rules[0].actions[0]", "rules[0].min_scor"}
+	if len(s.problems) != len(expected) {
+		t.Fatalf("expected %d problems, got: %v", len(expected), s.problems)
+	}
+	for i, path := range expected {
+		if !strings.HasPrefix(s.problems[i], path+":") {
+			t.Errorf("expected problem at %s, got: %s", path, s.problems[i])
+		}
+	}
+}
+
+// schemaChecker - упрощённая проверка YAML по схеме: неизвестные ключи, enum и pattern действий
+type schemaChecker struct {
+	defs     map[string]any
+	problems []string
+}
+
+func (s *schemaChecker) check(node *yaml.Node, schema map[string]any, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = s.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		// Ветка по виду узла: объект для mapping, строка или число для скаляра
+		for _, branch := range anyOf {
+			branch := branch.(map[string]any)
+			if (node.Kind == yaml.MappingNode) == (branch["type"] == "object") {
+				s.check(node, branch, path)
+				return
+			}
+		}
+		s.problems = append(s.problems, path+": unexpected value")
+		return
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		properties, _ := schema["properties"].(map[string]any)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			keyPath := strings.TrimPrefix(path+"."+key, ".")
+			if property, ok := properties[key].(map[string]any); ok {
+				s.check(value, property, keyPath)
+			} else if additional, ok := schema["additionalProperties"].(map[string]any); ok {
+				s.check(value, additional, keyPath)
+			} else {
+				s.problems = append(s.problems, keyPath+": unknown key")
+			}
+		}
+	case yaml.SequenceNode:
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range node.Content {
+			s.check(item, items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case yaml.ScalarNode:
+		if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, any(node.Value)) {
+			s.problems = append(s.problems, path+": value not in enum: "+node.Value)
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(node.Value) {
+			s.problems = append(s.problems, path+": value does not match pattern: "+node.Value)
+		}
+	}
+}
@@ -2,92 +2,430 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
 )
 
-// Validate проверяет корректность конфигурации
+// Issue - одна проблема в конфиге. Path - путь к настройке в YAML (rules[0].conditions[1].operator),
+// File, Line и Column - где она записана; пусто, если настройки нет в файле (конфиг собран в коде)
+type Issue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+func (i Issue) String() string {
+	if i.File == "" {
+		return fmt.Sprintf("%s: %s", i.Path, i.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", i.File, i.Line, i.Column, i.Path, i.Message)
+}
+
+// ValidationError - все проблемы конфига в порядке проверки
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Issues) == 1 {
+		return e.Issues[0].String()
+	}
+	lines := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		lines[i] = issue.String()
+	}
+	return fmt.Sprintf("%d problems:\n%s", len(e.Issues), strings.Join(lines, "\n"))
+}
+
+// Position - место настройки в файле конфига
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// Validate проверяет корректность конфигурации. Проверка не останавливается на первой
+// ошибке: возвращается *ValidationError со всеми найденными проблемами
 func Validate(cfg *Config) error {
-	if err := validateIMAP(&cfg.IMAP); err != nil {
-		return fmt.Errorf("IMAP config error: %w", err)
+	v := &validator{positions: cfg.positions}
+	v.imap(&cfg.IMAP)
+	v.rules(cfg.Rules, &cfg.Scoring, &cfg.Notifiers)
+	v.rateLimits(cfg.Notifiers.RateLimits)
+	v.quietHours(cfg.Notifiers.QuietHours)
+	v.monitoring(&cfg.Monitoring)
+	v.outbox(&cfg.Outbox)
+	v.digest(&cfg.Digest, &cfg.Notifiers)
+	v.dedup(&cfg.Dedup)
+	v.history(&cfg.History)
+	v.api(&cfg.API)
+	return v.err()
+}
+
+// validator собирает проблемы вместе с их местом в файлах
+type validator struct {
+	positions map[string]Position
+	issues    []Issue
+}
+
+func (v *validator) add(path, format string, args ...any) {
+	issue := Issue{Path: path, Message: fmt.Sprintf(format, args...)}
+	if pos, ok := v.locate(path); ok {
+		issue.File, issue.Line, issue.Column = pos.File, pos.Line, pos.Column
 	}
+	v.issues = append(v.issues, issue)
+}
 
-	if err := validateRules(cfg.Rules); err != nil {
-		return fmt.Errorf("rules config error: %w", err)
+// NewIssue создаёт проблему настройки path с местом в файле, если оно известно.
+// Для проверок вне пакета config, например шаблонов сообщений
+func (c *Config) NewIssue(path, message string) Issue {
+	v := &validator{positions: c.positions}
+	v.add(path, "%s", message)
+	return v.issues[0]
+}
+
+// locate ищет место настройки, а если её нет в файле (значение по умолчанию) -
+// ближайшего родителя: для rules[0].min_score это само правило
+func (v *validator) locate(path string) (Position, bool) {
+	for path != "" {
+		if pos, ok := v.positions[path]; ok {
+			return pos, true
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
 	}
+	return Position{}, false
+}
 
-	if err := validateMonitoring(&cfg.Monitoring); err != nil {
-		return fmt.Errorf("monitoring config error: %w", err)
+// describe - место настройки для ссылок из сообщений: файл и строка, если известны
+func (v *validator) describe(path string) string {
+	if pos, ok := v.locate(path); ok {
+		return fmt.Sprintf("%s:%d", pos.File, pos.Line)
 	}
+	return path
+}
 
-	return nil
+func (v *validator) err() error {
+	if len(v.issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: v.issues}
 }
 
-func validateIMAP(imap *IMAPConfig) error {
+func (v *validator) imap(imap *IMAPConfig) {
 	if imap.Server == "" {
-		return fmt.Errorf("server is required")
+		v.add("imap.server", "server is required")
 	}
 	if imap.Username == "" {
-		return fmt.Errorf("username is required")
+		v.add("imap.username", "username is required")
 	}
 	if imap.Password == "" {
-		return fmt.Errorf("password is required")
+		v.add("imap.password", "password is required")
 	}
 	if imap.Port <= 0 || imap.Port > 65535 {
-		return fmt.Errorf("invalid port: %d", imap.Port)
+		v.add("imap.port", "invalid port: %d", imap.Port)
 	}
-	return nil
 }
 
-func validateRules(rules []*models.Rule) error {
+// rules проверяет правила, их уникальность и общие пороги уровней.
+// notifiers == nil - правила без основного конфига (LoadRules): действия не сверяются с нотификаторами
+func (v *validator) rules(rules []*models.Rule, scoring *ScoringConfig, notifiers *NotifiersConfig) {
+	if scoring.Levels != nil {
+		v.levels("scoring.levels", *scoring.Levels, scoring.Normalize)
+	}
+
 	if len(rules) == 0 {
-		return fmt.Errorf("at least one rule is required")
+		v.add("rules", "at least one rule is required")
+		return
 	}
 
-	ruleNames := make(map[string]bool)
+	ruleNames := make(map[string]string)
+	ruleIDs := make(map[models.ID]string)
 	for i, rule := range rules {
+		path := fmt.Sprintf("rules[%d]", i)
 		if rule == nil {
-			return fmt.Errorf("rule %d is nil", i)
+			v.add(path, "rule is empty")
+			continue
 		}
 
-		// Валидируем само правило
-		if err := validateRule(rule); err != nil {
-			return fmt.Errorf("rule '%s' validation failed: %w", rule.Name, err)
+		v.rule(path, rule, scoring.Normalize, notifiers)
+
+		if first, ok := ruleNames[rule.Name]; ok && rule.Name != "" {
+			v.add(path+".name", "duplicate rule name: %s (first defined at %s)", rule.Name, v.describe(first))
+		} else {
+			ruleNames[rule.Name] = path
 		}
 
-		// Проверяем уникальность имен правил
-		if ruleNames[rule.Name] {
-			return fmt.Errorf("duplicate rule name: %s", rule.Name)
+		if rule.ID == "" {
+			continue
+		}
+		if first, ok := ruleIDs[rule.ID]; ok {
+			v.add(path+".id", "duplicate rule id: %s (first defined at %s)", rule.ID, v.describe(first))
+		} else {
+			ruleIDs[rule.ID] = path
 		}
-		ruleNames[rule.Name] = true
 	}
-
-	return nil
 }
 
-// Validate проверяет правило на валидность
-func validateRule(r *models.Rule) error {
+func (v *validator) rule(path string, r *models.Rule, normalize bool, notifiers *NotifiersConfig) {
 	if r.Name == "" {
-		return fmt.Errorf("rule name cannot be empty")
+		v.add(path+".name", "rule name cannot be empty")
 	}
 	if len(r.Conditions) == 0 {
-		return fmt.Errorf("rule must have at least one condition")
+		v.add(path+".conditions", "rule must have at least one condition")
+	}
+	for i, cond := range r.Conditions {
+		v.condition(fmt.Sprintf("%s.conditions[%d]", path, i), cond)
 	}
 	if len(r.Actions) == 0 {
-		return fmt.Errorf("rule must have at least one action")
+		v.add(path+".actions", "rule must have at least one action")
+	}
+	for i, action := range r.Actions {
+		v.action(fmt.Sprintf("%s.actions[%d]", path, i), action, notifiers)
+	}
+
+	if r.MinScore < 0 {
+		v.add(path+".min_score", "min_score cannot be negative")
+	}
+	// Без нормализации баллы - сумма весов и могут быть больше 100
+	if normalize && r.MinScore > 100 {
+		v.add(path+".min_score", "min_score must be between 0 and 100 when scoring is normalized")
+	}
+
+	// Правило, которое не может набрать min_score, никогда не сработает
+	if len(r.Conditions) > 0 {
+		reachable := r.MaxScore()
+		if normalize && reachable > 0 {
+			reachable = 100
+		}
+		if reachable < r.MinScore {
+			v.add(path+".min_score", "min_score %d is unreachable: maximum score is %d", r.MinScore, reachable)
+		}
+	}
+
+	if r.Levels != nil {
+		v.levels(path+".levels", *r.Levels, normalize)
+	}
+}
+
+func (v *validator) condition(path string, cond models.Condition) {
+	switch cond.Type {
+	case models.ConditionFrom, models.ConditionSubject, models.ConditionBody:
+	case models.ConditionHeader:
+		if cond.Field == "" {
+			v.add(path+".field", "header condition requires field")
+		}
+	default:
+		v.add(path+".type", "unknown condition type '%s' (expected one of: %s)", cond.Type, join(models.ConditionTypes))
+	}
+
+	switch cond.Operator {
+	case models.OperatorContains, models.OperatorEquals, models.OperatorStartsWith, models.OperatorEndsWith:
+	case models.OperatorMatches:
+		if _, err := regexp.Compile(cond.Value); err != nil {
+			v.add(path+".value", "invalid regular expression: %v", err)
+		}
+	default:
+		v.add(path+".operator", "unknown operator '%s' (expected one of: %s)", cond.Operator, join(models.Operators))
+	}
+
+	if cond.Weight < 0 {
+		v.add(path+".weight", "weight cannot be negative")
+	}
+}
+
+// action проверяет, что для действия есть включённый нотификатор и описан получатель
+func (v *validator) action(path string, action models.Action, notifiers *NotifiersConfig) {
+	if action.Type == "" {
+		v.add(path, "action type cannot be empty")
+		return
+	}
+	if notifiers == nil {
+		return
+	}
+	if !slices.Contains(models.ActionTypes, action.Type) {
+		v.add(path, "unknown action type '%s' (expected one of: %s)", action.Type, join(models.ActionTypes))
+		return
+	}
+	if !notifiers.IsEnabled(action.Type) {
+		v.add(path, "notifier %s is not configured or not enabled in notifiers", action.Type)
+		return
+	}
+	if action.Target != "" && !notifiers.HasTarget(action.Type, action.Target) {
+		v.add(path, "unknown target '%s' for %s", action.Target, action.Type)
 	}
-	if r.MinScore < 0 || r.MinScore > 100 {
-		return fmt.Errorf("min_score must be between 0 and 100")
+}
+
+func (v *validator) levels(path string, levels models.LevelThresholds, normalize bool) {
+	if levels.Medium < 0 || levels.High < levels.Medium || levels.Critical < levels.High {
+		v.add(path, "thresholds must satisfy 0 <= medium <= high <= critical")
+	}
+	if normalize && levels.Critical > 100 {
+		v.add(path+".critical", "thresholds must not exceed 100 when scoring is normalized")
 	}
-	return nil
 }
 
-func validateMonitoring(monitoring *MonitoringConfig) error {
+func (v *validator) rateLimits(limits map[models.ActionType]RateLimitConfig) {
+	// Порядок проблем не должен зависеть от порядка обхода map
+	types := make([]models.ActionType, 0, len(limits))
+	for actionType := range limits {
+		types = append(types, actionType)
+	}
+	slices.Sort(types)
+
+	for _, actionType := range types {
+		limit := limits[actionType]
+		path := "notifiers.rate_limits." + string(actionType)
+		if !slices.Contains(models.ActionTypes, actionType) {
+			v.add(path, "unknown action type '%s'", actionType)
+		}
+		if limit.PerMinute <= 0 {
+			v.add(path+".per_minute", "per_minute must be positive")
+		}
+		if limit.Burst < 1 {
+			v.add(path+".burst", "burst must be at least 1")
+		}
+	}
+}
+
+func (v *validator) quietHours(quiet *QuietHoursConfig) {
+	if quiet == nil || !quiet.Enabled {
+		return
+	}
+	const path = "notifiers.quiet_hours"
+	if _, err := quiet.GetLocation(); err != nil {
+		v.add(path+".timezone", "invalid timezone: %v", err)
+	}
+	if len(quiet.Schedule) == 0 {
+		v.add(path+".schedule", "schedule must have at least one window")
+	}
+	for i, window := range quiet.Schedule {
+		windowPath := fmt.Sprintf("%s.schedule[%d]", path, i)
+		from, fromErr := ParseClock(window.From)
+		if fromErr != nil {
+			v.add(windowPath+".from", "%v", fromErr)
+		}
+		to, toErr := ParseClock(window.To)
+		if toErr != nil {
+			v.add(windowPath+".to", "%v", toErr)
+		}
+		if fromErr == nil && toErr == nil && from == to {
+			v.add(windowPath, "from and to must differ")
+		}
+		for j, day := range window.Days {
+			if _, err := ParseWeekday(day); err != nil {
+				v.add(fmt.Sprintf("%s.days[%d]", windowPath, j), "%v", err)
+			}
+		}
+	}
+}
+
+func (v *validator) monitoring(monitoring *MonitoringConfig) {
 	if monitoring.CheckIntervalSeconds < 5 {
-		return fmt.Errorf("check_interval_seconds too small: %v", monitoring.CheckIntervalSeconds)
+		v.add("monitoring.check_interval_seconds", "check_interval_seconds too small: %v", monitoring.CheckIntervalSeconds)
 	}
 	if monitoring.MaxEmails <= 0 {
-		return fmt.Errorf("max_emails must be positive")
+		v.add("monitoring.max_emails", "max_emails must be positive")
+	}
+	if monitoring.StatsIntervalMinutes < 0 {
+		v.add("monitoring.stats_interval_minutes", "stats_interval_minutes must not be negative")
+	}
+}
+
+func (v *validator) outbox(outbox *OutboxConfig) {
+	if outbox.Path == "" {
+		v.add("outbox.path", "path is required")
+	}
+	if outbox.MaxAttempts <= 0 {
+		v.add("outbox.max_attempts", "max_attempts must be positive")
+	}
+	if outbox.InitialBackoffSeconds <= 0 {
+		v.add("outbox.initial_backoff_seconds", "initial_backoff_seconds must be positive")
+	}
+	if outbox.MaxBackoffSeconds < outbox.InitialBackoffSeconds {
+		v.add("outbox.max_backoff_seconds", "max_backoff_seconds must be >= initial_backoff_seconds")
+	}
+	if outbox.DeadRetentionDays < 0 {
+		v.add("outbox.dead_retention_days", "dead_retention_days must not be negative")
 	}
-	return nil
+	if outbox.MaxDead < 0 {
+		v.add("outbox.max_dead", "max_dead must not be negative")
+	}
+}
+
+func (v *validator) digest(digest *DigestConfig, notifiers *NotifiersConfig) {
+	if !digest.Enabled {
+		return
+	}
+	if digest.Path == "" {
+		v.add("digest.path", "path is required")
+	}
+	if digest.NearMissRatio < 0 || digest.NearMissRatio >= 1 {
+		v.add("digest.near_miss_ratio", "near_miss_ratio must be in [0, 1)")
+	}
+	if len(digest.Times) == 0 && digest.IntervalHours <= 0 {
+		v.add("digest.interval_hours", "interval_hours or times is required")
+	}
+	for i, t := range digest.Times {
+		if _, err := ParseClock(t); err != nil {
+			v.add(fmt.Sprintf("digest.times[%d]", i), "%v", err)
+		}
+	}
+	if _, err := digest.GetLocation(); err != nil {
+		v.add("digest.timezone", "invalid timezone: %v", err)
+	}
+	for i, action := range digest.Actions {
+		v.action(fmt.Sprintf("digest.actions[%d]", i), action, notifiers)
+	}
+}
+
+func (v *validator) dedup(dedup *DedupConfig) {
+	if !dedup.Enabled {
+		return
+	}
+	if dedup.Path == "" {
+		v.add("dedup.path", "path is required")
+	}
+	if dedup.WindowHours <= 0 {
+		v.add("dedup.window_hours", "window_hours must be positive")
+	}
+	switch dedup.Mode {
+	case DedupSuppress, DedupUpdate:
+	default:
+		v.add("dedup.mode", "unknown mode: %s", dedup.Mode)
+	}
+}
+
+func (v *validator) history(history *HistoryConfig) {
+	if !history.Enabled {
+		return
+	}
+	if history.Path == "" {
+		v.add("history.path", "path is required")
+	}
+	if history.RetentionDays < 0 {
+		v.add("history.retention_days", "retention_days must not be negative")
+	}
+}
+
+func (v *validator) api(api *APIConfig) {
+	if !api.Enabled {
+		return
+	}
+	if _, _, err := net.SplitHostPort(api.Listen); err != nil {
+		v.add("api.listen", "invalid listen address '%s': %v", api.Listen, err)
+	}
+}
+
+// join перечисляет допустимые значения через запятую
+func join[T ~string](values []T) string {
+	return strings.Join(stringValues(values), ", ")
 }
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
@@ -25,9 +28,61 @@ func TestValidate(t *testing.T) {
 					Weight:   10,
 				},
 			},
-			Actions: []models.ActionType{"telegram"},
+			Actions: []models.Action{{Type: "telegram"}},
 		},
 	}
+	unknownTargetCfg := *goodCfg
+	unknownTargetCfg.Rules = []*models.Rule{{
+		Name:       "С получателем",
+		Conditions: goodCfg.Rules[0].Conditions,
+		Actions:    []models.Action{{Type: "telegram", Target: "students"}},
+	}}
+
+	knownTargetCfg := unknownTargetCfg
+	knownTargetCfg.Notifiers.Telegram = &TelegramConfig{
+		Enabled: true,
+		Targets: map[string]TelegramTarget{"students": {ChatID: -100, ThreadID: 7}},
+	}
+
+	quietCfg := *goodCfg
+	quietCfg.Notifiers.QuietHours = &QuietHoursConfig{
+		Enabled:  true,
+		Timezone: "UTC",
+		Schedule: []QuietWindow{{Days: []string{"mon", "Friday"}, From: "23:00", To: "07:00"}},
+	}
+
+	badQuietCfg := *goodCfg
+	badQuietCfg.Notifiers.QuietHours = &QuietHoursConfig{
+		Enabled:  true,
+		Schedule: []QuietWindow{{From: "25:00", To: "07:00"}},
+	}
+
+	bigScoreCfg := *goodCfg
+	bigRule := *goodCfg.Rules[0]
+	bigRule.MinScore = 150
+	bigRule.Conditions = []models.Condition{{Type: "subject", Operator: "contains", Value: "срочно", Weight: 200}}
+	bigScoreCfg.Rules = []*models.Rule{&bigRule}
+
+	normalizedBigScoreCfg := bigScoreCfg
+	normalizedBigScoreCfg.Scoring = ScoringConfig{Normalize: true}
+
+	withRule := func(change func(rule *models.Rule)) Config {
+		cfg := *goodCfg
+		rule := *goodCfg.Rules[0]
+		rule.Conditions = slices.Clone(rule.Conditions)
+		change(&rule)
+		cfg.Rules = []*models.Rule{&rule}
+		return cfg
+	}
+
+	disabledCfg := *goodCfg
+	disabledCfg.Notifiers.Telegram = &TelegramConfig{BotToken: "token"}
+
+	duplicateIDCfg := *goodCfg
+	secondRule := *goodCfg.Rules[0]
+	secondRule.Name = "Другое"
+	duplicateIDCfg.Rules = []*models.Rule{goodCfg.Rules[0], &secondRule}
+
 	tests := []struct {
 		name    string
 		wantErr bool
@@ -38,6 +93,93 @@ func TestValidate(t *testing.T) {
 			wantErr: false,
 			cfg:     *goodCfg,
 		},
+		{
+			name:    "Неизвестный получатель действия",
+			wantErr: true,
+			cfg:     unknownTargetCfg,
+		},
+		{
+			name:    "Получатель описан в notifiers",
+			wantErr: false,
+			cfg:     knownTargetCfg,
+		},
+		{
+			name:    "Тихие часы",
+			wantErr: false,
+			cfg:     quietCfg,
+		},
+		{
+			name:    "Неверное время тихих часов",
+			wantErr: true,
+			cfg:     badQuietCfg,
+		},
+		{
+			name:    "min_score больше 100 без нормализации",
+			wantErr: false,
+			cfg:     bigScoreCfg,
+		},
+		{
+			name:    "min_score больше 100 с нормализацией",
+			wantErr: true,
+			cfg:     normalizedBigScoreCfg,
+		},
+		{
+			name:    "Неизвестный оператор",
+			wantErr: true,
+			cfg:     withRule(func(r *models.Rule) { r.Conditions[0].Operator = "like" }),
+		},
+		{
+			name:    "Неизвестный тип условия",
+			wantErr: true,
+			cfg:     withRule(func(r *models.Rule) { r.Conditions[0].Type = "to" }),
+		},
+		{
+			name:    "Заголовок без имени",
+			wantErr: true,
+			cfg:     withRule(func(r *models.Rule) { r.Conditions[0].Type = "header" }),
+		},
+		{
+			name:    "Неверное регулярное выражение",
+			wantErr: true,
+			cfg: withRule(func(r *models.Rule) {
+				r.Conditions[0].Operator = "matches"
+				r.Conditions[0].Value = "(срочно"
+			}),
+		},
+		{
+			name:    "Отрицательный вес",
+			wantErr: true,
+			cfg: withRule(func(r *models.Rule) {
+				r.Conditions = append(r.Conditions, models.Condition{Type: "from", Operator: "contains", Value: "noreply", Weight: -5})
+			}),
+		},
+		{
+			name:    "Нулевой вес",
+			wantErr: false,
+			cfg: withRule(func(r *models.Rule) {
+				r.Conditions = append(r.Conditions, models.Condition{Type: "from", Operator: "contains", Value: "noreply", Weight: 0})
+			}),
+		},
+		{
+			name:    "min_score недостижим",
+			wantErr: true,
+			cfg:     withRule(func(r *models.Rule) { r.MinScore = 11 }),
+		},
+		{
+			name:    "Неизвестный тип действия",
+			wantErr: true,
+			cfg:     withRule(func(r *models.Rule) { r.Actions = []models.Action{{Type: "email"}} }),
+		},
+		{
+			name:    "Нотификатор выключен",
+			wantErr: true,
+			cfg:     disabledCfg,
+		},
+		{
+			name:    "Повтор ID",
+			wantErr: true,
+			cfg:     duplicateIDCfg,
+		},
 		{
 			name:    "Нет конфига",
 			wantErr: true,
@@ -55,3 +197,52 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateIssues(t *testing.T) {
+	path := writeIncludeFiles(t, map[string]string{"config.yaml": `imap:
+  server: imap.example.com
+  username: user
+  password: secret
+rules:
+  - name: Срочное
+    min_score: 30
+    conditions:
+      - type: subject
+        operator: like
+        value: срочно
+        weight: 20
+      - type: body
+        operator: matches
+        value: "(дедлайн"
+        weight: 10
+    actions: [telegram, email]
+monitoring:
+  check_interval_seconds: 1
+`})
+
+	_, err := Load(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got: %v", err)
+	}
+
+	// Проверка не останавливается на первой проблеме
+	expected := []Issue{
+		{Path: "rules[0].conditions[0].operator", Line: 10, Column: 19},
+		{Path: "rules[0].conditions[1].value", Line: 15, Column: 16},
+		{Path: "rules[0].actions[1]", Line: 17, Column: 25},
+		{Path: "monitoring.check_interval_seconds", Line: 19, Column: 27},
+	}
+	if len(verr.Issues) != len(expected) {
+		t.Fatalf("expected %d issues, got: %v", len(expected), verr)
+	}
+	for i, want := range expected {
+		got := verr.Issues[i]
+		if got.Path != want.Path || got.File != path || got.Line != want.Line || got.Column != want.Column {
+			t.Errorf("issue %d: expected %s at %d:%d, got: %s", i, want.Path, want.Line, want.Column, got)
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "config validation failed: 4 problems:") {
+		t.Errorf("unexpected error text: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce - сколько ждём после последнего события: редакторы пишут файл в несколько приёмов
+const watchDebounce = 500 * time.Millisecond
+
+// Watch вызывает onChange после каждого изменения файлов конфига, пока не отменён ctx.
+// paths - файлы и каталоги с правилами (см. Config.Files): в каталоге учитываются все *.yaml и *.yml.
+// За файлом следим через его каталог: редакторы и systemd/kubernetes заменяют файл
+// через rename, и наблюдение за самим файлом после этого теряется.
+// Список путей определяется при запуске: новые include подхватятся после перезапуска
+func Watch(ctx context.Context, paths []string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	files := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		dir := filepath.Dir(path)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirs[path] = true
+			dir = path
+		} else {
+			files[path] = true
+		}
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch config directory: %w", err)
+		}
+	}
+
+	changed := func(name string) bool {
+		name = filepath.Clean(name)
+		if files[name] {
+			return true
+		}
+		ext := filepath.Ext(name)
+		return dirs[filepath.Dir(name)] && (ext == ".yaml" || ext == ".yml")
+	}
+
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// В каталоге правил удаление файла тоже меняет набор правил
+			if changed(event.Name) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+				timer.Reset(watchDebounce)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("Ошибка наблюдения за конфигом: %v", err)
+
+		case <-timer.C:
+			onChange()
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
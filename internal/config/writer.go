@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"go.yaml.in/yaml/v3"
+)
+
+// SaveRule заменяет правило с номером index в списке rules файла path на rule.
+// Файл правится через yaml.Node: меняются только изменившиеся значения,
+// поэтому комментарии, кавычки и остальные блоки остаются как были
+func SaveRule(path string, index int, rule *models.Rule) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	item, err := findRuleNode(&doc, index, rule.ID)
+	if err != nil {
+		return err
+	}
+
+	var updated yaml.Node
+	if err := updated.Encode(rule); err != nil {
+		return fmt.Errorf("failed to encode rule: %w", err)
+	}
+	// У правила без id в файле не появляется пустой id
+	if rule.ID == "" {
+		deleteKey(&updated, "id")
+	}
+	mergeNode(item, &updated)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// findRuleNode возвращает элемент index списка rules. Если у элемента есть id,
+// он должен совпасть с id правила: иначе файл изменился после загрузки
+func findRuleNode(doc *yaml.Node, index int, id models.ID) (*yaml.Node, error) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("config file is empty")
+	}
+
+	root := doc.Content[0]
+	rules := mappingValue(root, "rules")
+	if rules == nil || rules.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("rules not found in config file")
+	}
+
+	if index < 0 || index >= len(rules.Content) {
+		return nil, fmt.Errorf("rule #%d not found in config file", index)
+	}
+	item := rules.Content[index]
+	if idNode := mappingValue(item, "id"); idNode != nil && idNode.Value != string(id) {
+		return nil, fmt.Errorf("rule #%d in config file has id '%s', expected '%s'", index, idNode.Value, id)
+	}
+	return item, nil
+}
+
+// mergeNode переносит значения из src в dst, сохраняя у dst комментарии и стиль записи
+func mergeNode(dst, src *yaml.Node) {
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		// Порядок ключей dst сохраняется, ключи, которых больше нет (omitempty), пропадают,
+		// новые добавляются в конец
+		var content []*yaml.Node
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			key, value := dst.Content[i], dst.Content[i+1]
+			if updated := mappingValue(src, key.Value); updated != nil {
+				mergeNode(value, updated)
+				content = append(content, key, value)
+			}
+		}
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			if mappingValue(dst, src.Content[i].Value) == nil {
+				content = append(content, src.Content[i], src.Content[i+1])
+			}
+		}
+		dst.Content = content
+
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		for i, item := range src.Content {
+			if i < len(dst.Content) {
+				mergeNode(dst.Content[i], item)
+			} else {
+				dst.Content = append(dst.Content, item)
+			}
+		}
+		dst.Content = dst.Content[:len(src.Content)]
+
+	case dst.Kind == yaml.ScalarNode && src.Kind == yaml.ScalarNode:
+		if dst.Tag != src.Tag {
+			dst.Style = src.Style
+		}
+		dst.Tag = src.Tag
+		dst.Value = src.Value
+
+	default:
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+	}
+}
+
+// mappingValue возвращает значение ключа key в mapping-узле
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// deleteKey убирает ключ key из mapping-узла
+func deleteKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// writeFileAtomic записывает файл через временный и rename, сохраняя права исходного
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, mode); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tmpFile, path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
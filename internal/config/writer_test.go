@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"go.yaml.in/yaml/v3"
+)
+
+const writerConfig = `# Конфиг мониторинга
+imap:
+  server: imap.example.com
+
+rules:
+  # Медосмотр - главное правило
+  - id: "rule-1"
+    name: "Медосмотр"
+    enabled: true
+    min_score: 70
+    conditions:
+      - type: subject
+        operator: contains
+        value: "медосмотр"
+        weight: 80
+    actions: ["telegram"]
+  - id: "rule-2"
+    name: "Дедлайн"
+    enabled: true
+    min_score: 50
+    conditions:
+      - type: subject
+        operator: contains
+        value: "дедлайн"
+        weight: 60
+    actions: ["telegram"]
+`
+
+func TestSaveRule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(writerConfig), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rule := &models.Rule{
+		ID:         "rule-1",
+		Name:       "Медосмотр",
+		Enabled:    false,
+		MinScore:   40,
+		Conditions: []models.Condition{{Type: models.ConditionSubject, Operator: models.OperatorContains, Value: "медосмотр", Weight: 50}},
+		Actions:    []models.Action{{Type: models.ActionNotifyTelegram, Target: "staff"}},
+	}
+	if err := SaveRule(path, 0, rule); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, comment := range []string{"# Конфиг мониторинга", "# Медосмотр - главное правило"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("comment %q lost:\n%s", comment, data)
+		}
+	}
+
+	var saved struct {
+		Rules []*models.Rule `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(saved.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got: %d", len(saved.Rules))
+	}
+	got := saved.Rules[0]
+	if got.Enabled || got.MinScore != 40 || got.Conditions[0].Weight != 50 || got.Actions[0].Target != "staff" {
+		t.Errorf("rule not updated: %+v", got)
+	}
+	if saved.Rules[1].Name != "Дедлайн" || saved.Rules[1].MinScore != 50 {
+		t.Errorf("other rule changed: %+v", saved.Rules[1])
+	}
+
+	if err := SaveRule(path, 2, &models.Rule{ID: "rule-3"}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+	if err := SaveRule(path, 1, &models.Rule{ID: "rule-1"}); err == nil {
+		t.Error("expected error for rule with another id")
+	}
+}
+
+func TestSaveRuleWithoutID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := strings.ReplaceAll(writerConfig, "  - id: \"rule-2\"\n    name:", "  - name:")
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rule := &models.Rule{
+		Name:       "Сроки сдачи",
+		Enabled:    true,
+		MinScore:   30,
+		Conditions: []models.Condition{{Type: models.ConditionSubject, Operator: models.OperatorContains, Value: "дедлайн", Weight: 60}},
+		Actions:    []models.Action{{Type: models.ActionNotifyTelegram}},
+	}
+	if err := SaveRule(path, 1, rule); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var saved struct {
+		Rules []*models.Rule `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := saved.Rules[1]; got.Name != "Сроки сдачи" || got.MinScore != 30 {
+		t.Errorf("rule not updated: %+v", got)
+	}
+	if strings.Count(string(data), "id:") != 1 {
+		t.Errorf("empty id must not be written:\n%s", data)
+	}
+}
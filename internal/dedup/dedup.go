@@ -0,0 +1,260 @@
+package dedup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// Entry - отправленный алерт и признаки, по которым узнаются его повторы
+type Entry struct {
+	AlertID    models.ID `json:"alert_id"`
+	Rule       string    `json:"rule"`
+	MessageIDs []string  `json:"message_ids"` // письма цепочки
+	Subject    string    `json:"subject"`     // нормализованная тема
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	Repeats    int       `json:"repeats"`
+	// Pending - алерт ещё не доставлен: повторами к нему ничего не считается,
+	// иначе неудачная первая отправка подавила бы и все следующие
+	Pending bool `json:"pending,omitempty"`
+	// Messages - отправленные сообщения (нотификатор и чат -> message_id),
+	// чтобы повторы обновляли их и после перезапуска
+	Messages map[string]int `json:"messages,omitempty"`
+}
+
+// Store - журнал отправленных алертов для поиска повторов.
+// Повтором считается алерт того же правила, если совпадает Message-ID,
+// письмо отвечает в ту же цепочку (In-Reply-To/References) или совпадает
+// тема после нормализации. Записи живут window с последнего повтора.
+type Store struct {
+	path   string
+	window time.Duration
+
+	mu      sync.Mutex
+	entries []*Entry
+	now     func() time.Time
+}
+
+// New создаёт журнал и загружает его из файла
+func New(cfg *config.DedupConfig) (*Store, error) {
+	s := &Store{
+		path:   cfg.Path,
+		window: cfg.GetWindow(),
+		now:    time.Now,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Check ищет прежний доставленный алерт, повтором которого является alert.
+// Для повтора возвращает копию записи с учётом этого повтора, иначе запоминает
+// alert как новый и возвращает nil. Повторы к новому алерту начнут находиться
+// после Delivered
+func (s *Store) Check(alert *models.Alert) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expireLocked(now)
+
+	var messageID string
+	if alert.Email != nil {
+		messageID = models.NormalizeMessageID(alert.Email.MessageID)
+	}
+
+	entry := s.findLocked(alert)
+	if entry != nil {
+		entry.LastSeen = now
+		entry.Repeats++
+		if messageID != "" && !slices.Contains(entry.MessageIDs, messageID) {
+			entry.MessageIDs = append(entry.MessageIDs, messageID)
+		}
+	} else {
+		entry = &Entry{
+			AlertID:   alert.ID,
+			Rule:      alert.Rule.Name,
+			FirstSeen: now,
+			LastSeen:  now,
+			Pending:   true,
+		}
+		if messageID != "" {
+			entry.MessageIDs = []string{messageID}
+		}
+		if alert.Email != nil {
+			entry.Subject = NormalizeSubject(alert.Email.Subject)
+		}
+		s.entries = append(s.entries, entry)
+	}
+
+	if err := s.saveLocked(); err != nil {
+		return nil, fmt.Errorf("ошибка сохранения журнала повторов: %w", err)
+	}
+
+	if entry.Repeats == 0 {
+		return nil, nil
+	}
+	found := *entry
+	found.Messages = nil
+	return &found, nil
+}
+
+// Delivered отмечает, что алерт доставлен хотя бы одним действием
+func (s *Store) Delivered(alertID models.ID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entryLocked(alertID)
+	if entry == nil || !entry.Pending {
+		return nil
+	}
+	entry.Pending = false
+	if err := s.saveLocked(); err != nil {
+		return fmt.Errorf("ошибка сохранения журнала повторов: %w", err)
+	}
+	return nil
+}
+
+// SaveMessage запоминает сообщение, отправленное для алерта (см. notifier.MessageStore)
+func (s *Store) SaveMessage(alertID models.ID, key string, messageID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entryLocked(alertID)
+	if entry == nil {
+		return nil // алерт без журнала повторов, например сводка
+	}
+	if entry.Messages == nil {
+		entry.Messages = make(map[string]int)
+	}
+	entry.Messages[key] = messageID
+	if err := s.saveLocked(); err != nil {
+		return fmt.Errorf("ошибка сохранения журнала повторов: %w", err)
+	}
+	return nil
+}
+
+// Message возвращает сообщение, отправленное для алерта
+func (s *Store) Message(alertID models.ID, key string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entryLocked(alertID)
+	if entry == nil {
+		return 0, false
+	}
+	messageID, ok := entry.Messages[key]
+	return messageID, ok
+}
+
+// entryLocked ищет запись по ID алерта
+func (s *Store) entryLocked(alertID models.ID) *Entry {
+	for _, entry := range s.entries {
+		if entry.AlertID == alertID {
+			return entry
+		}
+	}
+	return nil
+}
+
+// findLocked ищет запись того же правила, к которой относится алерт
+func (s *Store) findLocked(alert *models.Alert) *Entry {
+	if alert.Email == nil {
+		return nil
+	}
+
+	ids := alert.Email.ThreadIDs()
+	if id := models.NormalizeMessageID(alert.Email.MessageID); id != "" {
+		ids = append(ids, id)
+	}
+	subject := NormalizeSubject(alert.Email.Subject)
+
+	for _, entry := range s.entries {
+		if entry.Pending || entry.Rule != alert.Rule.Name {
+			continue
+		}
+		if subject != "" && subject == entry.Subject {
+			return entry
+		}
+		for _, id := range ids {
+			if slices.Contains(entry.MessageIDs, id) {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// expireLocked забывает записи, повторов которых не было дольше окна
+func (s *Store) expireLocked(now time.Time) {
+	s.entries = slices.DeleteFunc(s.entries, func(entry *Entry) bool {
+		return now.Sub(entry.LastSeen) > s.window
+	})
+}
+
+// subjectPrefix - служебные приставки к теме: Re:, Fwd:, Напоминание: и т.п.
+var subjectPrefix = regexp.MustCompile(`^(re|fw|fwd|ответ|пересл|напоминание|reminder|повторно|повтор|upd)\s*(\[\d+\]|\(\d+\))?\s*:\s*`)
+
+// NormalizeSubject приводит тему к виду для сравнения: нижний регистр,
+// без служебных приставок, лишних пробелов и точек в конце
+func NormalizeSubject(subject string) string {
+	subject = strings.ToLower(strings.Join(strings.Fields(subject), " "))
+	for {
+		trimmed := subjectPrefix.ReplaceAllString(subject, "")
+		if trimmed == subject {
+			break
+		}
+		subject = trimmed
+	}
+	return strings.TrimRight(subject, ".! ")
+}
+
+// load читает журнал из файла
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Первый запуск
+		}
+		return fmt.Errorf("ошибка чтения журнала повторов: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return fmt.Errorf("ошибка демаршалинга журнала повторов: %w", err)
+	}
+	return nil
+}
+
+// saveLocked атомарно записывает журнал в файл
+func (s *Store) saveLocked() error {
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("ошибка создания директории: %w", err)
+	}
+
+	tmpFile := s.path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return fmt.Errorf("ошибка записи временного файла: %w", err)
+	}
+
+	if err := os.Rename(tmpFile, s.path); err != nil {
+		return fmt.Errorf("ошибка переименовывания файла: %w", err)
+	}
+	return nil
+}
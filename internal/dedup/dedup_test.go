@@ -0,0 +1,142 @@
+package dedup
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func testStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := New(&config.DedupConfig{
+		Enabled:     true,
+		Path:        filepath.Join(t.TempDir(), "dedup.json"),
+		WindowHours: 24,
+		Mode:        config.DedupSuppress,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return s
+}
+
+func testAlert(id models.ID, rule, messageID, subject string, headers map[string]string) *models.Alert {
+	return &models.Alert{
+		ID:    id,
+		Rule:  &models.Rule{Name: rule},
+		Email: &models.Email{MessageID: messageID, Subject: subject, Headers: headers},
+	}
+}
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		subject  string
+		expected string
+	}{
+		{"Запись на медосмотр", "запись на медосмотр"},
+		{"Напоминание: запись на медосмотр!", "запись на медосмотр"},
+		{"RE: Fwd:  Запись   на медосмотр", "запись на медосмотр"},
+		{"Re[2]: запись на медосмотр.", "запись на медосмотр"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			if got := NormalizeSubject(tt.subject); got != tt.expected {
+				t.Errorf("incorrect result, expected: '%v', got: '%v'", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := testStore(t)
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	first := testAlert("alert-1", "Медосмотр", "<a1@hse.ru>", "Запись на медосмотр", nil)
+	if entry, _ := s.Check(first); entry != nil {
+		t.Fatalf("first alert is not a repeat, got: %+v", entry)
+	}
+	if err := s.Delivered("alert-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Напоминание с другой темой, но в той же цепочке
+	reply := testAlert("alert-2", "Медосмотр", "<a2@hse.ru>", "Последний день записи",
+		map[string]string{"In-Reply-To": "<A1@hse.ru>"})
+	entry, err := s.Check(reply)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry == nil || entry.AlertID != "alert-1" || entry.Repeats != 1 {
+		t.Fatalf("expected repeat of alert-1, got: %+v", entry)
+	}
+
+	// Ответ на напоминание тоже относится к цепочке
+	chained := testAlert("alert-3", "Медосмотр", "<a3@hse.ru>", "Спасибо",
+		map[string]string{"References": "<a1@hse.ru> <a2@hse.ru>"})
+	if entry, _ := s.Check(chained); entry == nil || entry.Repeats != 2 {
+		t.Errorf("expected second repeat, got: %+v", entry)
+	}
+
+	// Та же тема, но другое правило - не повтор
+	other := testAlert("alert-4", "Рассылки", "<a4@hse.ru>", "Напоминание: запись на медосмотр", nil)
+	if entry, _ := s.Check(other); entry != nil {
+		t.Errorf("other rule is not a repeat, got: %+v", entry)
+	}
+
+	// Переотправленное письмо с той же темой
+	resent := testAlert("alert-5", "Медосмотр", "<a5@hse.ru>", "Напоминание: запись на медосмотр", nil)
+	if entry, _ := s.Check(resent); entry == nil || entry.AlertID != "alert-1" {
+		t.Errorf("expected repeat by subject, got: %+v", entry)
+	}
+
+	// После окна повторов запись забывается
+	now = now.Add(25 * time.Hour)
+	late := testAlert("alert-6", "Медосмотр", "<a6@hse.ru>", "Запись на медосмотр", nil)
+	if entry, _ := s.Check(late); entry != nil {
+		t.Errorf("entry must expire after window, got: %+v", entry)
+	}
+}
+
+func TestStoreSurvivesRestart(t *testing.T) {
+	s := testStore(t)
+	s.Check(testAlert("alert-1", "Медосмотр", "<a1@hse.ru>", "Запись на медосмотр", nil))
+	s.Delivered("alert-1")
+	if err := s.SaveMessage("alert-1", "telegram:42", 7); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restarted, err := New(&config.DedupConfig{Path: s.path, WindowHours: 24})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry, _ := restarted.Check(testAlert("alert-2", "Медосмотр", "<a1@hse.ru>", "", nil)); entry == nil {
+		t.Error("expected repeat after restart, got nil")
+	}
+	if messageID, ok := restarted.Message("alert-1", "telegram:42"); !ok || messageID != 7 {
+		t.Errorf("expected message 7 after restart, got: %d, %v", messageID, ok)
+	}
+}
+
+func TestCheckPending(t *testing.T) {
+	s := testStore(t)
+
+	// Первая отправка не удалась: следующее письмо в цепочке - не повтор
+	s.Check(testAlert("alert-1", "Медосмотр", "<a1@hse.ru>", "Запись на медосмотр", nil))
+	reply := testAlert("alert-2", "Медосмотр", "<a2@hse.ru>", "Запись на медосмотр",
+		map[string]string{"In-Reply-To": "<a1@hse.ru>"})
+	if entry, _ := s.Check(reply); entry != nil {
+		t.Fatalf("undelivered alert must not suppress repeats, got: %+v", entry)
+	}
+
+	if err := s.Delivered("alert-2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resent := testAlert("alert-3", "Медосмотр", "<a3@hse.ru>", "Запись на медосмотр", nil)
+	if entry, _ := s.Check(resent); entry == nil || entry.AlertID != "alert-2" {
+		t.Errorf("expected repeat of delivered alert-2, got: %+v", entry)
+	}
+}
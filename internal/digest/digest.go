@@ -0,0 +1,249 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// checkInterval - как часто проверяем, не пора ли отправить дайджест
+const checkInterval = time.Minute
+
+// SendFunc отправляет дайджест через действие
+type SendFunc func(action models.Action, alert *models.Alert) error
+
+// state - то, что хранится в файле между запусками
+type state struct {
+	Items    []*models.Alert `json:"items"`
+	LastSent time.Time       `json:"last_sent"`
+}
+
+// Digest копит малозначимые алерты и по расписанию отправляет их одной сводкой
+// на каждое действие. Накопленное хранится на диске и переживает перезапуск.
+type Digest struct {
+	path     string
+	send     SendFunc
+	actions  []models.Action
+	maxLevel models.AlertLevel
+	interval time.Duration
+	times    []int // минуты от полуночи
+	loc      *time.Location
+
+	mu    sync.Mutex
+	state state
+	now   func() time.Time
+}
+
+// New создаёт дайджест и загружает накопленное в прошлый раз
+func New(cfg *config.DigestConfig, send SendFunc) (*Digest, error) {
+	loc, err := cfg.GetLocation()
+	if err != nil {
+		return nil, fmt.Errorf("неизвестный часовой пояс %s: %w", cfg.Timezone, err)
+	}
+
+	d := &Digest{
+		path:     cfg.Path,
+		send:     send,
+		actions:  cfg.Actions,
+		maxLevel: cfg.GetMaxLevel(),
+		interval: cfg.GetInterval(),
+		loc:      loc,
+		now:      time.Now,
+	}
+
+	for _, t := range cfg.Times {
+		minutes, err := config.ParseClock(t)
+		if err != nil {
+			return nil, err
+		}
+		d.times = append(d.times, minutes)
+	}
+
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	if d.state.LastSent.IsZero() {
+		// Первый запуск: отсчитываем расписание от текущего момента
+		d.state.LastSent = d.now()
+	}
+
+	if len(d.state.Items) > 0 {
+		log.Printf("В дайджесте накоплено с прошлого запуска: %d", len(d.state.Items))
+	}
+	return d, nil
+}
+
+// Accepts проверяет, должен ли алерт уйти в дайджест вместо мгновенного уведомления
+func (d *Digest) Accepts(alert *models.Alert) bool {
+	return alert.NearMiss || alert.Level <= d.maxLevel
+}
+
+// Add добавляет алерт в дайджест
+func (d *Digest) Add(alert *models.Alert) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.state.Items = append(d.state.Items, alert)
+	if err := d.saveLocked(); err != nil {
+		return fmt.Errorf("ошибка сохранения дайджеста: %w", err)
+	}
+	return nil
+}
+
+// Len возвращает количество накопленных алертов
+func (d *Digest) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.state.Items)
+}
+
+// Run отправляет дайджест по расписанию, пока не отменён ctx
+func (d *Digest) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.flushDue()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// flushDue отправляет дайджест, если пришло время
+func (d *Digest) flushDue() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if d.nextRun(d.state.LastSent).After(now) {
+		return
+	}
+
+	if len(d.state.Items) > 0 {
+		if err := d.sendLocked(); err != nil {
+			// Накопленное остаётся, попробуем на следующей проверке
+			log.Printf("Ошибка отправки дайджеста: %v", err)
+			return
+		}
+		log.Printf("Дайджест отправлен: %d писем", len(d.state.Items))
+	}
+
+	d.state.Items = nil
+	d.state.LastSent = now
+	if err := d.saveLocked(); err != nil {
+		log.Printf("Ошибка сохранения дайджеста: %v", err)
+	}
+}
+
+// sendLocked собирает по сводке на каждое действие и отправляет их
+func (d *Digest) sendLocked() error {
+	byAction := make(map[models.Action][]*models.Alert)
+	seen := make(map[models.Action]map[models.ID]bool)
+
+	for _, alert := range d.state.Items {
+		actions := d.actions
+		if len(actions) == 0 && alert.Rule != nil {
+			actions = alert.Rule.Actions
+		}
+
+		for _, action := range actions {
+			// Письмо, почти подошедшее под несколько правил, показываем один раз
+			if seen[action] == nil {
+				seen[action] = make(map[models.ID]bool)
+			}
+			if alert.Email != nil && seen[action][alert.Email.ID] {
+				continue
+			}
+			if alert.Email != nil {
+				seen[action][alert.Email.ID] = true
+			}
+			byAction[action] = append(byAction[action], alert)
+		}
+	}
+
+	actions := make([]models.Action, 0, len(byAction))
+	for action := range byAction {
+		actions = append(actions, action)
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i].String() < actions[j].String() })
+
+	for _, action := range actions {
+		alerts := byAction[action]
+		title := fmt.Sprintf("Дайджест: %d писем ниже порога", len(alerts))
+		if err := d.send(action, models.NewSummaryAlert(title, nil, alerts)); err != nil {
+			return fmt.Errorf("%s: %w", action, err)
+		}
+	}
+	return nil
+}
+
+// nextRun возвращает время следующей отправки после last
+func (d *Digest) nextRun(last time.Time) time.Time {
+	if len(d.times) == 0 {
+		return last.Add(d.interval)
+	}
+
+	last = last.In(d.loc)
+	midnight := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, d.loc)
+
+	var next time.Time
+	for day := 0; day <= 1; day++ {
+		for _, minutes := range d.times {
+			candidate := midnight.AddDate(0, 0, day).Add(time.Duration(minutes) * time.Minute)
+			if candidate.After(last) && (next.IsZero() || candidate.Before(next)) {
+				next = candidate
+			}
+		}
+	}
+	return next
+}
+
+// load читает накопленное из файла
+func (d *Digest) load() error {
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Первый запуск
+		}
+		return fmt.Errorf("ошибка чтения дайджеста: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &d.state); err != nil {
+		return fmt.Errorf("ошибка демаршалинга дайджеста: %w", err)
+	}
+	return nil
+}
+
+// saveLocked атомарно записывает накопленное в файл
+func (d *Digest) saveLocked() error {
+	data, err := json.Marshal(d.state)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return fmt.Errorf("ошибка создания директории: %w", err)
+	}
+
+	tmpFile := d.path + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return fmt.Errorf("ошибка записи временного файла: %w", err)
+	}
+
+	if err := os.Rename(tmpFile, d.path); err != nil {
+		return fmt.Errorf("ошибка переименовывания файла: %w", err)
+	}
+	return nil
+}
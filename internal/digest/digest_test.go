@@ -0,0 +1,106 @@
+package digest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+type sent struct {
+	action models.Action
+	alert  *models.Alert
+}
+
+func testAlert(email *models.Email, rule *models.Rule, score int) *models.Alert {
+	alert := models.NewAlert(email, rule, score, "")
+	alert.NearMiss = true
+	return alert
+}
+
+func TestDigestSchedule(t *testing.T) {
+	cfg := &config.DigestConfig{
+		Enabled:  true,
+		Path:     filepath.Join(t.TempDir(), "digest.json"),
+		Times:    []string{"09:00", "18:00"},
+		Timezone: "UTC",
+	}
+
+	var got []sent
+	d, err := New(cfg, func(action models.Action, alert *models.Alert) error {
+		got = append(got, sent{action, alert})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	d.state.LastSent = now
+
+	telegram := models.Action{Type: models.ActionNotifyTelegram}
+	slack := models.Action{Type: models.ActionNotifySlack}
+	rule := &models.Rule{Name: "Медосмотр", MinScore: 60, Actions: []models.Action{telegram}}
+	other := &models.Rule{Name: "Рассылки", MinScore: 60, Actions: []models.Action{telegram, slack}}
+
+	email := &models.Email{ID: "email-1", Subject: "Медосмотр?", From: "med@hse.ru", Links: []string{"https://hse.ru"}}
+	d.Add(testAlert(email, rule, 40))
+	d.Add(testAlert(email, other, 35)) // то же письмо по другому правилу
+	d.Add(testAlert(&models.Email{ID: "email-2", Subject: "Новости"}, other, 30))
+
+	// До 18:00 дайджест не уходит
+	now = time.Date(2025, 10, 1, 17, 59, 0, 0, time.UTC)
+	d.flushDue()
+	if len(got) != 0 {
+		t.Fatalf("digest sent too early, got: %d", len(got))
+	}
+
+	now = time.Date(2025, 10, 1, 18, 0, 0, 0, time.UTC)
+	d.flushDue()
+	if len(got) != 2 || d.Len() != 0 {
+		t.Fatalf("expected 2 digests and empty queue, got: %d digests, %d items", len(got), d.Len())
+	}
+	if got[0].action != slack || len(got[0].alert.Group) != 2 {
+		t.Errorf("incorrect slack digest, got: %v with %d items", got[0].action, len(got[0].alert.Group))
+	}
+	if got[1].action != telegram || len(got[1].alert.Group) != 2 {
+		t.Errorf("email must appear once in telegram digest, got: %d items", len(got[1].alert.Group))
+	}
+
+	// Следующая отправка - завтра в 09:00
+	if next := d.nextRun(d.state.LastSent); !next.Equal(time.Date(2025, 10, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("incorrect next run, got: %v", next)
+	}
+}
+
+func TestDigestSurvivesRestart(t *testing.T) {
+	cfg := &config.DigestConfig{
+		Enabled:       true,
+		Path:          filepath.Join(t.TempDir(), "digest.json"),
+		IntervalHours: 6,
+	}
+	noop := func(models.Action, *models.Alert) error { return nil }
+
+	d, err := New(cfg, noop)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rule := &models.Rule{Name: "Медосмотр", MinScore: 60}
+	if err := d.Add(testAlert(&models.Email{ID: "email-1"}, rule, 40)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	restarted, err := New(cfg, noop)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restarted.Len() != 1 {
+		t.Errorf("expected 1 item after restart, got: %d", restarted.Len())
+	}
+	if !restarted.state.LastSent.Equal(d.state.LastSent) {
+		t.Errorf("schedule must survive restart, got: %v", restarted.state.LastSent)
+	}
+}
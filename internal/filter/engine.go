@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/metrics"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
 )
 
 type Engine struct {
-	rules []*models.Rule
+	rules         []*models.Rule
+	nearMissRatio float64 // 0 - почти сработавшие правила не собираются
+	scoring       config.ScoringConfig
+	metrics       bool // учитывать проверки и срабатывания в метриках /metrics
 }
 
 // NewEngine - создает новый движок правил
@@ -18,9 +23,45 @@ func NewEngine(r []*models.Rule) *Engine {
 	}
 }
 
+// NewConfiguredEngine создаёт движок для правил rules с настройками подсчёта
+// и сбором почти сработавших правил (для дайджеста) из конфига
+func NewConfiguredEngine(cfg *config.Config, rules []*models.Rule) *Engine {
+	engine := NewEngine(rules)
+	engine.SetScoring(cfg.Scoring)
+	if cfg.Digest.Enabled {
+		engine.SetNearMissRatio(cfg.Digest.NearMissRatio)
+	}
+	return engine
+}
+
+// EnableMetrics включает учёт проверок и срабатываний правил в метриках.
+// Только для движка, который разбирает письма из ящика: теневые правила, rules test
+// и backfill проверяют те же письма и правила с теми же именами
+func (e *Engine) EnableMetrics() {
+	e.metrics = true
+}
+
+// SetNearMissRatio включает сбор почти сработавших правил:
+// письмо, набравшее не меньше ratio*MinScore, попадает в nearMisses (см. Evaluate)
+func (e *Engine) SetNearMissRatio(ratio float64) {
+	e.nearMissRatio = ratio
+}
+
+// SetScoring задаёт модель подсчёта: нормализацию баллов, общие пороги уровней
+// и повышение уровня для приоритетных правил
+func (e *Engine) SetScoring(scoring config.ScoringConfig) {
+	e.scoring = scoring
+}
+
 // Process - обрабатывает письмо через все правила
 func (e *Engine) Process(email *models.Email) []*models.Alert {
-	var alerts []*models.Alert = make([]*models.Alert, 0)
+	alerts, _ := e.Evaluate(email)
+	return alerts
+}
+
+// Evaluate - обрабатывает письмо и возвращает алерты и почти сработавшие правила
+func (e *Engine) Evaluate(email *models.Email) (alerts, nearMisses []*models.Alert) {
+	alerts = make([]*models.Alert, 0)
 
 	for _, rule := range e.rules {
 		if rule == nil {
@@ -32,34 +73,86 @@ func (e *Engine) Process(email *models.Email) []*models.Alert {
 			continue
 		}
 
+		if e.metrics {
+			metrics.RuleEvaluations.Inc(rule.Name)
+		}
 		alert, err := e.evaluateRule(rule, email)
 		if err != nil {
 			log.Printf("Ошибка обработки правила: %v", err)
 			continue
 		}
 
-		if alert != nil {
+		switch {
+		case alert == nil:
+		case alert.NearMiss:
+			nearMisses = append(nearMisses, alert)
+		default:
+			if e.metrics {
+				metrics.RuleMatches.Inc(rule.Name)
+			}
 			alerts = append(alerts, alert)
 		}
 	}
 
-	return alerts
+	return alerts, nearMisses
 }
 
 // evaluateRule - применяет одно правило к письму
 func (e *Engine) evaluateRule(rule *models.Rule, email *models.Email) (*models.Alert, error) {
-	score, reasons, err := e.calculateScore(rule, email)
-	if err != nil {
-		return nil, err
+	trace := e.traceRule(rule, email)
+	if trace.Err != nil {
+		return nil, trace.Err
 	}
+	return e.buildAlert(email, trace), nil
+}
 
-	if score >= rule.MinScore {
+// buildAlert - создаёт алерт по разбору правила, nil если правило не сработало
+func (e *Engine) buildAlert(email *models.Email, trace RuleTrace) *models.Alert {
+	rule := trace.Rule
+	score := trace.Score
+
+	if trace.Matched {
 		reasonText := fmt.Sprintf("Правило: %s. Баллы: %d/%d. Причины: %s",
-			rule.Name, score, rule.MinScore, reasons)
+			rule.Name, score, rule.MinScore, trace.Reasons())
 
 		alert := models.NewAlert(email, rule, score, reasonText)
-		return alert, nil
+		e.applyLevel(alert)
+		return alert
+	}
+
+	if e.nearMissRatio > 0 && score > 0 && float64(score) >= e.nearMissRatio*float64(rule.MinScore) {
+		reasonText := fmt.Sprintf("Правило: %s. Не хватило баллов: %d/%d. Причины: %s",
+			rule.Name, score, rule.MinScore, trace.Reasons())
+
+		alert := models.NewAlert(email, rule, score, reasonText)
+		alert.NearMiss = true
+		return alert
+	}
+
+	return nil
+}
+
+// applyLevel пересчитывает уровень алерта по модели подсчёта
+func (e *Engine) applyLevel(alert *models.Alert) {
+	thresholds := alert.Rule.GetThresholds()
+	if alert.Rule.Levels == nil && e.scoring.Levels != nil {
+		thresholds = *e.scoring.Levels
+	}
+
+	level, reason := thresholds.Level(alert.Score)
+	if boost := e.scoring.PriorityBoost; boost > 0 && alert.Rule.Priority >= boost && level < models.AlertCritical {
+		reason = fmt.Sprintf("%s; приоритет правила %d ≥ %d: %s → %s",
+			reason, alert.Rule.Priority, boost, level, level+1)
+		level++
 	}
 
-	return nil, nil
+	alert.SetLevel(level, reason)
+}
+
+// normalizeScore переводит баллы в 0-100 от максимально возможных
+func normalizeScore(score, maxScore int) int {
+	if maxScore <= 0 {
+		return 0
+	}
+	return min((score*100+maxScore/2)/maxScore, 100)
 }
@@ -0,0 +1,127 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/metrics"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func testRule() *models.Rule {
+	return &models.Rule{
+		Name:     "Медосмотр",
+		Enabled:  true,
+		Priority: 90,
+		MinScore: 50,
+		Conditions: []models.Condition{
+			{Type: models.ConditionSubject, Operator: models.OperatorContains, Value: "медосмотр", Weight: 120},
+			{Type: models.ConditionFrom, Operator: models.OperatorContains, Value: "hse.ru", Weight: 80},
+		},
+		Actions: []models.Action{{Type: models.ActionNotifyTelegram}},
+	}
+}
+
+func TestScoring(t *testing.T) {
+	email := &models.Email{Subject: "Запись на медосмотр", From: "news@example.com"}
+
+	tests := []struct {
+		name          string
+		scoring       config.ScoringConfig
+		expectedScore int
+		expectedLevel models.AlertLevel
+	}{
+		{
+			name:          "Пороги от min_score",
+			expectedScore: 120,
+			expectedLevel: models.AlertCritical,
+		},
+		{
+			name:          "Нормализация",
+			scoring:       config.ScoringConfig{Normalize: true},
+			expectedScore: 60,
+			expectedLevel: models.AlertMedium,
+		},
+		{
+			name: "Общие пороги и приоритет",
+			scoring: config.ScoringConfig{
+				Normalize:     true,
+				Levels:        &models.LevelThresholds{Medium: 40, High: 70, Critical: 90},
+				PriorityBoost: 80,
+			},
+			expectedScore: 60,
+			expectedLevel: models.AlertHigh,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine([]*models.Rule{testRule()})
+			engine.SetScoring(tt.scoring)
+
+			alerts := engine.Process(email)
+			if len(alerts) != 1 {
+				t.Fatalf("expected 1 alert, got: %d", len(alerts))
+			}
+			if alerts[0].Score != tt.expectedScore || alerts[0].Level != tt.expectedLevel {
+				t.Errorf("incorrect result, expected: %d/%v, got: %d/%v (%s)", tt.expectedScore, tt.expectedLevel,
+					alerts[0].Score, alerts[0].Level, alerts[0].LevelReason)
+			}
+			if alerts[0].LevelReason == "" {
+				t.Error("level must be explained")
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	rule := testRule()
+	rule.Conditions[1].Operator = models.OperatorEndsWith
+	engine := NewEngine([]*models.Rule{rule})
+
+	email := &models.Email{Subject: "Открыта запись на медосмотр", From: "news@example.com"}
+	traces := engine.Explain(email)
+	if len(traces) != 1 {
+		t.Fatalf("expected 1 trace, got: %d", len(traces))
+	}
+
+	trace := traces[0]
+	if !trace.Matched || trace.Score != 120 || trace.Level != models.AlertCritical {
+		t.Errorf("incorrect trace, got: %+v", trace)
+	}
+	if len(trace.Conditions) != 2 {
+		t.Fatalf("every condition must be traced, got: %d", len(trace.Conditions))
+	}
+
+	subject, from := trace.Conditions[0], trace.Conditions[1]
+	if !subject.Matched || subject.Added != 120 || subject.Excerpt != email.Subject {
+		t.Errorf("incorrect subject trace, got: %+v", subject)
+	}
+	if from.Matched || from.Added != 0 {
+		t.Errorf("incorrect from trace, got: %+v", from)
+	}
+	if reason := from.Reason(); reason != "Отправитель заканчивается на hse.ru" {
+		t.Errorf("incorrect reason, got: '%v'", reason)
+	}
+}
+
+func TestEngineMetrics(t *testing.T) {
+	rule := testRule()
+	rule.Name = "Метрики"
+	email := &models.Email{Subject: "Запись на медосмотр", From: "med@hse.ru"}
+
+	// Теневой движок и CLI не пишут метрики, иначе письмо посчитается дважды
+	NewEngine([]*models.Rule{rule}).Evaluate(email)
+	if got := metrics.RuleEvaluations.Value(rule.Name); got != 0 {
+		t.Errorf("expected no evaluations without metrics, got: %v", got)
+	}
+
+	live := NewEngine([]*models.Rule{rule})
+	live.EnableMetrics()
+	live.Evaluate(email)
+	if got := metrics.RuleEvaluations.Value(rule.Name); got != 1 {
+		t.Errorf("expected 1 evaluation, got: %v", got)
+	}
+	if got := metrics.RuleMatches.Value(rule.Name); got != 1 {
+		t.Errorf("expected 1 match, got: %v", got)
+	}
+}
@@ -7,59 +7,117 @@ import (
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
 )
 
-// calculateScore - считает баллы полученные письмом за правило
-func (e *Engine) calculateScore(rule *models.Rule, email *models.Email) (int, string, error) {
-	var score int = 0
-	var reasons []string = make([]string, 0)
+// traceRule - проверяет все условия правила и считает баллы
+func (e *Engine) traceRule(rule *models.Rule, email *models.Email) RuleTrace {
+	trace := RuleTrace{Rule: rule}
 
 	for _, condition := range rule.Conditions {
-		check, reason, err := e.evaluateCondition(condition, email)
-		if err != nil {
-			return 0, "", err
-		}
-
-		if check {
-			score += condition.Weight
-			reasons = append(reasons, reason)
+		cond := e.evaluateCondition(condition, email)
+		trace.Conditions = append(trace.Conditions, cond)
+		if cond.Err != nil {
+			trace.Err = cond.Err
+			return trace
 		}
+		trace.RawScore += cond.Added
 	}
 
-	return score, strings.Join(reasons, ", "), nil
+	trace.Score = trace.RawScore
+	if e.scoring.Normalize {
+		trace.Score = normalizeScore(trace.RawScore, rule.MaxScore())
+	}
+	trace.Matched = trace.Score >= rule.MinScore
+	return trace
 }
 
 // evaluateCondition - проверяет выполняет ли письмо условие
-func (e *Engine) evaluateCondition(cond models.Condition, email *models.Email) (bool, string, error) {
+func (e *Engine) evaluateCondition(cond models.Condition, email *models.Email) ConditionTrace {
+	trace := ConditionTrace{Condition: cond}
+
 	var value string
-	var fieldName string
 	switch cond.Type {
 	case models.ConditionBody:
 		value = email.Body
-		fieldName = "Тело"
+		trace.Field = "Тело"
 
 	case models.ConditionFrom:
 		value = email.From
-		fieldName = "Отправитель"
+		trace.Field = "Отправитель"
 
 	case models.ConditionHeader:
 		value = email.Headers[cond.Field]
-		fieldName = fmt.Sprintf("Заголовок %s", cond.Field)
+		trace.Field = fmt.Sprintf("Заголовок %s", cond.Field)
 
 	case models.ConditionSubject:
 		value = email.Subject
-		fieldName = "Тема"
+		trace.Field = "Тема"
 
 	default:
-		return false, "", fmt.Errorf("неизвестный тип условия: %s", cond.Operator)
+		trace.Err = fmt.Errorf("неизвестный тип условия: %s", cond.Type)
+		return trace
 	}
 
-	check, err := e.checkCondition(cond, value)
-	if err != nil {
-		return false, "", err
+	trace.Matched, trace.Err = e.checkCondition(cond, value)
+	trace.Excerpt = excerpt(cond, value)
+	if trace.Matched {
+		trace.Added = cond.Weight
 	}
+	return trace
+}
 
-	if check {
-		return true, fmt.Sprintf("%s содержит %s", fieldName, cond.Value), nil
+// operatorText - как оператор звучит в объяснении
+func operatorText(op models.Operator) string {
+	switch op {
+	case models.OperatorContains:
+		return "содержит"
+	case models.OperatorEquals:
+		return "равно"
+	case models.OperatorStartsWith:
+		return "начинается с"
+	case models.OperatorEndsWith:
+		return "заканчивается на"
+	case models.OperatorMatches:
+		return "соответствует"
+	default:
+		return string(op)
 	}
+}
+
+// excerptRadius - сколько символов показывать вокруг совпадения
+const excerptRadius = 30
 
-	return false, "", nil
+// excerpt возвращает фрагмент значения поля: вокруг совпадения или начало
+func excerpt(cond models.Condition, value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	runes := []rune(value)
+
+	start := 0
+	if cond.Operator == models.OperatorContains || cond.Operator == models.OperatorMatches {
+		lower := []rune(strings.ToLower(value))
+		if idx := runeIndex(lower, []rune(cond.Value)); idx >= 0 {
+			start = max(idx-excerptRadius, 0)
+		}
+	}
+
+	end := min(start+2*excerptRadius+len([]rune(cond.Value)), len(runes))
+	text := string(runes[start:end])
+	if start > 0 {
+		text = "…" + text
+	}
+	if end < len(runes) {
+		text += "…"
+	}
+	return text
+}
+
+// runeIndex ищет подстроку в рунах. Для регулярных выражений ищет как обычный текст
+func runeIndex(text, sub []rune) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := 0; i+len(sub) <= len(text); i++ {
+		if string(text[i:i+len(sub)]) == string(sub) {
+			return i
+		}
+	}
+	return -1
 }
@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// ConditionTrace - результат проверки одного условия
+type ConditionTrace struct {
+	Condition models.Condition
+	Field     string // название поля письма
+	Excerpt   string // фрагмент значения поля
+	Matched   bool
+	Added     int // баллы, добавленные условием
+	Err       error
+}
+
+// Reason объясняет условие: "Тема содержит срочно"
+func (c ConditionTrace) Reason() string {
+	return fmt.Sprintf("%s %s %s", c.Field, operatorText(c.Condition.Operator), c.Condition.Value)
+}
+
+// RuleTrace - разбор правила для письма: все условия, баллы и итоговый уровень
+type RuleTrace struct {
+	Rule        *models.Rule
+	Conditions  []ConditionTrace
+	RawScore    int // сумма весов сработавших условий
+	Score       int // баллы после нормализации
+	Matched     bool
+	NearMiss    bool
+	Level       models.AlertLevel // 0, если алерта нет
+	LevelReason string
+	Err         error
+}
+
+// Reasons возвращает объяснения сработавших условий через запятую
+func (t RuleTrace) Reasons() string {
+	var reasons []string
+	for _, cond := range t.Conditions {
+		if cond.Matched {
+			reasons = append(reasons, cond.Reason())
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// String форматирует разбор для вывода в консоль или лог
+func (t RuleTrace) String() string {
+	var sb strings.Builder
+
+	verdict := "не сработало"
+	switch {
+	case t.Err != nil:
+		verdict = fmt.Sprintf("ошибка: %v", t.Err)
+	case !t.Rule.Enabled:
+		verdict = "правило выключено"
+	case t.Matched:
+		verdict = fmt.Sprintf("сработало, уровень %s: %s", t.Level, t.LevelReason)
+	case t.NearMiss:
+		verdict = "почти сработало, в дайджест"
+	}
+
+	score := fmt.Sprintf("%d/%d", t.Score, t.Rule.MinScore)
+	if t.Score != t.RawScore {
+		score = fmt.Sprintf("%s (сумма весов %d из %d)", score, t.RawScore, t.Rule.MaxScore())
+	}
+	fmt.Fprintf(&sb, "Правило %q: %s, баллы %s\n", t.Rule.Name, verdict, score)
+
+	for _, cond := range t.Conditions {
+		mark := "✗"
+		if cond.Matched {
+			mark = "✓"
+		}
+		fmt.Fprintf(&sb, "  %s %s: %+d\n", mark, cond.Reason(), cond.Added)
+		if cond.Err != nil {
+			fmt.Fprintf(&sb, "      ошибка: %v\n", cond.Err)
+		}
+		fmt.Fprintf(&sb, "      значение: %q\n", cond.Excerpt)
+	}
+	return sb.String()
+}
+
+// Explain разбирает письмо всеми правилами, включая выключенные и несработавшие
+func (e *Engine) Explain(email *models.Email) []RuleTrace {
+	var traces []RuleTrace
+	for _, rule := range e.rules {
+		if rule == nil {
+			continue
+		}
+
+		trace := e.traceRule(rule, email)
+		if trace.Err == nil && rule.Enabled {
+			if alert := e.buildAlert(email, trace); alert != nil {
+				trace.NearMiss = alert.NearMiss
+				if !alert.NearMiss {
+					trace.Level = alert.Level
+					trace.LevelReason = alert.LevelReason
+				}
+			}
+		}
+		traces = append(traces, trace)
+	}
+	return traces
+}
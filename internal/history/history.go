@@ -0,0 +1,361 @@
+package history
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Бакеты базы. Ключ записи - время (8 байт big-endian UnixNano) и ID,
+// поэтому записи в бакете упорядочены по времени
+var (
+	bucketEmails     = []byte("emails")
+	bucketAlerts     = []byte("alerts")
+	bucketDeliveries = []byte("deliveries")
+)
+
+// pruneInterval - как часто удаляем записи старше срока хранения
+const pruneInterval = time.Hour
+
+// Outcome - что стало с алертом после фильтра
+type Outcome string
+
+const (
+	OutcomeQueued     Outcome = "queued"     // поставлен в очередь уведомлений
+	OutcomeDigest     Outcome = "digest"     // ушёл в дайджест
+	OutcomeSuppressed Outcome = "suppressed" // повтор, не отправлялся
+)
+
+// EmailRecord - обработанное письмо, только метаданные
+type EmailRecord struct {
+	ID          models.ID `json:"id"`
+	MessageID   string    `json:"message_id,omitempty"`
+	From        string    `json:"from"`
+	Subject     string    `json:"subject"`
+	Date        time.Time `json:"date"`
+	Size        int       `json:"size,omitempty"`
+	Rules       []string  `json:"rules,omitempty"` // сработавшие правила
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// AlertRecord - алерт и его судьба
+type AlertRecord struct {
+	ID          models.ID         `json:"id"`
+	EmailID     models.ID         `json:"email_id"`
+	Subject     string            `json:"subject"`
+	From        string            `json:"from"`
+	RuleID      models.ID         `json:"rule_id"`
+	Rule        string            `json:"rule"`
+	Score       int               `json:"score"`
+	Level       models.AlertLevel `json:"level"`
+	LevelReason string            `json:"level_reason,omitempty"`
+	NearMiss    bool              `json:"near_miss,omitempty"`
+	ReplacesID  models.ID         `json:"replaces_id,omitempty"`
+	Outcome     Outcome           `json:"outcome"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// DeliveryRecord - одна попытка доставки уведомления
+type DeliveryRecord struct {
+	EntryID models.ID `json:"entry_id"` // запись в очереди уведомлений
+	AlertID models.ID `json:"alert_id"`
+	Rule    string    `json:"rule"`
+	Action  string    `json:"action"`
+	Attempt int       `json:"attempt"`
+	State   string    `json:"state"`
+	Error   string    `json:"error,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Query - фильтр выборки. Нулевые поля не ограничивают выборку.
+// Записи возвращаются от новых к старым
+type Query struct {
+	Since   time.Time
+	Until   time.Time
+	Rule    string    // имя правила
+	AlertID models.ID // только для доставок
+	Limit   int
+}
+
+// Counts - количество записей в базе
+type Counts struct {
+	Emails     int `json:"emails"`
+	Alerts     int `json:"alerts"`
+	Deliveries int `json:"deliveries"`
+}
+
+// Store - история писем, алертов и доставок во встроенной базе bbolt
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+	now       func() time.Time
+}
+
+// Open открывает (или создаёт) базу истории
+func Open(cfg *config.HistoryConfig) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания директории истории: %w", err)
+	}
+
+	db, err := bolt.Open(cfg.Path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия истории %s: %w", cfg.Path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketEmails, bucketAlerts, bucketDeliveries} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка инициализации истории: %w", err)
+	}
+
+	return &Store{
+		db:        db,
+		retention: cfg.GetRetention(),
+		now:       time.Now,
+	}, nil
+}
+
+// Close закрывает базу
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Run периодически удаляет записи старше срока хранения, пока не отменён ctx
+func (s *Store) Run(ctx context.Context) {
+	if s.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if removed, err := s.Prune(); err != nil {
+			log.Printf("Ошибка очистки истории: %v", err)
+		} else if removed > 0 {
+			log.Printf("Из истории удалено устаревших записей: %d", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecordEmail сохраняет метаданные обработанного письма и сработавшие правила
+func (s *Store) RecordEmail(email *models.Email, alerts []*models.Alert) error {
+	record := EmailRecord{
+		ID:          email.ID,
+		MessageID:   email.MessageID,
+		From:        email.From,
+		Subject:     email.Subject,
+		Date:        email.Date,
+		Size:        email.Size,
+		ProcessedAt: s.now(),
+	}
+	for _, alert := range alerts {
+		record.Rules = append(record.Rules, alert.Rule.Name)
+	}
+	return s.put(bucketEmails, record.ProcessedAt, record.ID, record)
+}
+
+// RecordAlert сохраняет алерт и то, что с ним сделано
+func (s *Store) RecordAlert(alert *models.Alert, outcome Outcome) error {
+	record := NewAlertRecord(alert, outcome)
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = s.now()
+	}
+	return s.put(bucketAlerts, record.CreatedAt, record.ID, record)
+}
+
+// NewAlertRecord создаёт запись истории для алерта
+func NewAlertRecord(alert *models.Alert, outcome Outcome) AlertRecord {
+	record := AlertRecord{
+		ID:          alert.ID,
+		RuleID:      alert.Rule.ID,
+		Rule:        alert.Rule.Name,
+		Score:       alert.Score,
+		Level:       alert.Level,
+		LevelReason: alert.LevelReason,
+		NearMiss:    alert.NearMiss,
+		ReplacesID:  alert.ReplacesID,
+		Outcome:     outcome,
+		CreatedAt:   alert.CreatedAt,
+	}
+	if alert.Email != nil {
+		record.EmailID = alert.Email.ID
+		record.Subject = alert.Email.Subject
+		record.From = alert.Email.From
+	}
+	return record
+}
+
+// RecordDelivery сохраняет попытку доставки. Пустое время заменяется текущим
+func (s *Store) RecordDelivery(record DeliveryRecord) error {
+	if record.At.IsZero() {
+		record.At = s.now()
+	}
+	key := models.ID(fmt.Sprintf("%s-%d", record.EntryID, record.Attempt))
+	return s.put(bucketDeliveries, record.At, key, record)
+}
+
+// Emails возвращает обработанные письма
+func (s *Store) Emails(q Query) ([]EmailRecord, error) {
+	return query(s.db, bucketEmails, q, func(r *EmailRecord) bool {
+		if q.Rule == "" {
+			return true
+		}
+		for _, rule := range r.Rules {
+			if rule == q.Rule {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Alerts возвращает алерты
+func (s *Store) Alerts(q Query) ([]AlertRecord, error) {
+	return query(s.db, bucketAlerts, q, func(r *AlertRecord) bool {
+		return q.Rule == "" || r.Rule == q.Rule
+	})
+}
+
+// Deliveries возвращает попытки доставки
+func (s *Store) Deliveries(q Query) ([]DeliveryRecord, error) {
+	return query(s.db, bucketDeliveries, q, func(r *DeliveryRecord) bool {
+		return (q.Rule == "" || r.Rule == q.Rule) && (q.AlertID == "" || r.AlertID == q.AlertID)
+	})
+}
+
+// Counts возвращает количество записей каждого вида
+func (s *Store) Counts() (Counts, error) {
+	var counts Counts
+	err := s.db.View(func(tx *bolt.Tx) error {
+		counts.Emails = tx.Bucket(bucketEmails).Stats().KeyN
+		counts.Alerts = tx.Bucket(bucketAlerts).Stats().KeyN
+		counts.Deliveries = tx.Bucket(bucketDeliveries).Stats().KeyN
+		return nil
+	})
+	return counts, err
+}
+
+// Prune удаляет записи старше срока хранения и возвращает их количество
+func (s *Store) Prune() (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := timeKey(s.now().Add(-s.retention))
+
+	var removed int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketEmails, bucketAlerts, bucketDeliveries} {
+			bucket := tx.Bucket(name)
+
+			// Удаляем после обхода: удаление под курсором сбивает Next
+			var expired [][]byte
+			c := bucket.Cursor()
+			for k, _ := c.First(); k != nil && string(k[:8]) < string(cutoff); k, _ = c.Next() {
+				expired = append(expired, k)
+			}
+			for _, k := range expired {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+			removed += len(expired)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка очистки истории: %w", err)
+	}
+	return removed, nil
+}
+
+// put сохраняет запись под ключом время+ID
+func (s *Store) put(bucket []byte, at time.Time, id models.ID, record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга записи истории: %w", err)
+	}
+
+	key := append(timeKey(at), id...)
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, data)
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка записи в историю: %w", err)
+	}
+	return nil
+}
+
+// query обходит бакет от новых записей к старым в пределах q.Since..q.Until
+func query[T any](db *bolt.DB, bucket []byte, q Query, match func(*T) bool) ([]T, error) {
+	records := make([]T, 0)
+	err := db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+
+		k, v := c.Last()
+		if !q.Until.IsZero() {
+			// Встаём на первую запись после Until и шагаем назад
+			k, v = c.Seek(timeKey(q.Until.Add(time.Nanosecond)))
+			if k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+
+		var since []byte
+		if !q.Since.IsZero() {
+			since = timeKey(q.Since)
+		}
+
+		for ; k != nil; k, v = c.Prev() {
+			if since != nil && string(k[:8]) < string(since) {
+				break
+			}
+
+			var record T
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("ошибка чтения записи истории: %w", err)
+			}
+			if !match(&record) {
+				continue
+			}
+
+			records = append(records, record)
+			if q.Limit > 0 && len(records) >= q.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return records, err
+}
+
+// timeKey кодирует время так, чтобы ключи сортировались по времени
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
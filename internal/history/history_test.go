@@ -0,0 +1,112 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func testStore(t *testing.T, path string, now *time.Time) *Store {
+	t.Helper()
+	s, err := Open(&config.HistoryConfig{Enabled: true, Path: path, RetentionDays: 7})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.now = func() time.Time { return *now }
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func testAlert(id models.ID, rule string, createdAt time.Time) *models.Alert {
+	return &models.Alert{
+		ID:        id,
+		Rule:      &models.Rule{ID: models.ID("rule-" + rule), Name: rule},
+		Email:     &models.Email{ID: "email-" + id, Subject: "Тема " + string(id)},
+		Score:     80,
+		Level:     models.AlertHigh,
+		CreatedAt: createdAt,
+	}
+}
+
+func TestRecordAndQuery(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := testStore(t, filepath.Join(t.TempDir(), "history.db"), &now)
+
+	for i, rule := range []string{"Медосмотр", "Дедлайн", "Медосмотр"} {
+		alert := testAlert(models.ID(rune('a'+i)), rule, now.Add(time.Duration(i)*time.Minute))
+		if err := s.RecordEmail(alert.Email, []*models.Alert{alert}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.RecordAlert(alert, OutcomeQueued); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	err := s.RecordDelivery(DeliveryRecord{EntryID: "e1", AlertID: "c", Rule: "Медосмотр", Action: "telegram", Attempt: 1, State: "delivered"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	alerts, err := s.Alerts(Query{Rule: "Медосмотр"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alerts) != 2 || alerts[0].ID != "c" || alerts[1].ID != "a" {
+		t.Fatalf("expected alerts c, a (newest first), got: %+v", alerts)
+	}
+	if alerts[0].Subject != "Тема c" || alerts[0].Outcome != OutcomeQueued || alerts[0].Level != models.AlertHigh {
+		t.Errorf("incorrect alert record: %+v", alerts[0])
+	}
+
+	alerts, _ = s.Alerts(Query{Since: now.Add(time.Minute), Until: now.Add(time.Minute)})
+	if len(alerts) != 1 || alerts[0].ID != "b" {
+		t.Errorf("expected only alert b in range, got: %+v", alerts)
+	}
+
+	alerts, _ = s.Alerts(Query{Limit: 1})
+	if len(alerts) != 1 || alerts[0].ID != "c" {
+		t.Errorf("expected newest alert c, got: %+v", alerts)
+	}
+
+	emails, _ := s.Emails(Query{Rule: "Дедлайн"})
+	if len(emails) != 1 || emails[0].ID != "email-b" {
+		t.Errorf("expected email-b, got: %+v", emails)
+	}
+
+	deliveries, _ := s.Deliveries(Query{AlertID: "c"})
+	if len(deliveries) != 1 || deliveries[0].Action != "telegram" || !deliveries[0].At.Equal(now) {
+		t.Errorf("incorrect deliveries: %+v", deliveries)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := testStore(t, path, &now)
+
+	s.RecordAlert(testAlert("old", "Медосмотр", now.Add(-8*24*time.Hour)), OutcomeQueued)
+	s.RecordAlert(testAlert("new", "Медосмотр", now.Add(-time.Hour)), OutcomeDigest)
+
+	removed, err := s.Prune()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 removed record, got: %d", removed)
+	}
+
+	// История переживает перезапуск
+	s.Close()
+	s = testStore(t, path, &now)
+
+	counts, _ := s.Counts()
+	if counts.Alerts != 1 {
+		t.Errorf("expected 1 alert after reopen, got: %+v", counts)
+	}
+	alerts, _ := s.Alerts(Query{})
+	if len(alerts) != 1 || alerts[0].ID != "new" || alerts[0].Outcome != OutcomeDigest {
+		t.Errorf("incorrect alerts after prune: %+v", alerts)
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/metrics"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
@@ -17,17 +22,29 @@ import (
 type Client struct {
 	config    *config.Config
 	client    *client.Client
-	connected bool
-	lastUid   uint32
+	connected atomic.Bool // читается из HTTP API, поэтому атомарно
+	lost      atomic.Bool // сервер закрыл соединение, при следующей проверке переподключаемся
 	stateFile string
+	readOnly  bool // dry-run: ящик открывается через EXAMINE, состояние не сохраняется
+
+	paused  atomic.Bool   // Watch пропускает плановые проверки
+	checkCh chan struct{} // внеочередная проверка, см. CheckNow
+
+	// lastUid сохраняется только после Ack: письмо, уведомления по которому не попали
+	// в очередь, будет забрано снова при следующей проверке
+	mu      sync.Mutex
+	lastUid uint32
+	fetched []uint32        // UID из последней проверки по возрастанию, ещё не вошедшие в lastUid
+	acked   map[uint32]bool // обработанные UID больше lastUid
 }
 
 // NewIMAP создает новый IMAP клиент
 func NewIMAPClient(cfg *config.Config) *Client {
 	client := &Client{
 		config:    cfg,
-		connected: false,
 		stateFile: "data/mail_state.json",
+		checkCh:   make(chan struct{}, 1),
+		acked:     make(map[uint32]bool),
 	}
 	client.loadState()
 	return client
@@ -89,6 +106,12 @@ func (c *Client) saveState() error {
 	return nil
 }
 
+// SetReadOnly включает режим только для чтения: письма не помечаются прочитанными,
+// а lastUid двигается только в памяти и не записывается в файл состояния
+func (c *Client) SetReadOnly(readOnly bool) {
+	c.readOnly = readOnly
+}
+
 // Connect устанавливает соединение с IMAP сервером
 func (c *Client) Connect() error {
 	var err error
@@ -111,7 +134,7 @@ func (c *Client) Connect() error {
 		return fmt.Errorf("ошибка авторизации: %w", err)
 	}
 
-	c.connected = true
+	c.connected.Store(true)
 	log.Printf("Успешное подключение к почтовому ящику")
 
 	return nil
@@ -119,27 +142,36 @@ func (c *Client) Connect() error {
 
 // GetNewEmails возвращает новые письма
 func (c *Client) GetNewEmails() ([]*models.Email, error) {
-	if !c.connected {
-		return nil, fmt.Errorf("клиент не подключен")
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
 	}
 
+	mailboxName := c.config.IMAP.Mailbox
+	start := time.Now()
+	defer metrics.IMAPFetchDuration.ObserveSince(start, mailboxName)
+
 	// Выбираем почтовый ящик
-	mailbox, err := c.client.Select(c.config.IMAP.Mailbox, false)
+	mailbox, err := c.client.Select(c.config.IMAP.Mailbox, c.readOnly)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка выбора ящика: %w", err)
 	}
 
 	// Если нет писем вообще
 	if mailbox.Messages == 0 {
+		metrics.LastCheckSuccess.SetTime(time.Now(), mailboxName)
 		return []*models.Email{}, nil
 	}
 
+	c.mu.Lock()
+	lastUid := c.lastUid
+	c.mu.Unlock()
+
 	// Получаем письма только с UID больше последнего обработанного
 	// Создаем команду UidSearch для поиска UID > lastUid
 	criteria := &imap.SearchCriteria{
 		Uid: new(imap.SeqSet),
 	}
-	criteria.Uid.AddRange(c.lastUid+1, 0) // От lastUid + 1 до конца
+	criteria.Uid.AddRange(lastUid+1, 0) // От lastUid + 1 до конца
 
 	uids, err := c.client.UidSearch(criteria)
 	if err != nil {
@@ -147,12 +179,13 @@ func (c *Client) GetNewEmails() ([]*models.Email, error) {
 	}
 
 	// Если нет новых писем
-	if len(uids) == 0 || (len(uids) == 1 && uids[0] == c.lastUid) {
+	if len(uids) == 0 || (len(uids) == 1 && uids[0] == lastUid) {
+		metrics.LastCheckSuccess.SetTime(time.Now(), mailboxName)
 		return []*models.Email{}, nil
 	}
 
 	// Либо берём последние MaxEmails писем
-	from := c.lastUid + 1
+	from := lastUid + 1
 	if len(uids) > c.config.Monitoring.MaxEmails {
 		from = uids[len(uids)-c.config.Monitoring.MaxEmails]
 	}
@@ -160,11 +193,169 @@ func (c *Client) GetNewEmails() ([]*models.Email, error) {
 	seqset := new(imap.SeqSet)
 	seqset.AddRange(from, 0)
 
+	emails, fetched, err := c.fetch(seqset, c.readOnly)
+	if err != nil {
+		return nil, err
+	}
+	emails, err = c.track(emails, fetched, from-1)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сохранения состояния: %w", err)
+	}
+
+	metrics.EmailsFetched.Add(float64(len(emails)), mailboxName)
+	metrics.LastCheckSuccess.SetTime(time.Now(), mailboxName)
+
+	log.Printf("Найдено писем: %d", len(emails))
+	return emails, nil
+}
+
+// ensureConnected переподключается, если сервер закрыл соединение между проверками
+// (таймаут простоя, перезапуск сервера). Не удалось - попробуем на следующей проверке
+func (c *Client) ensureConnected() error {
+	if c.connected.Load() {
+		if c.client.State() != imap.LogoutState {
+			return nil
+		}
+		log.Println("Соединение с IMAP сервером потеряно, переподключаемся...")
+		c.connected.Store(false)
+		c.lost.Store(true)
+	}
+	if !c.lost.Load() {
+		return fmt.Errorf("клиент не подключен")
+	}
+
+	if err := c.Connect(); err != nil {
+		return fmt.Errorf("ошибка переподключения: %w", err)
+	}
+	c.lost.Store(false)
+	metrics.IMAPReconnects.Inc()
+	return nil
+}
+
+// track запоминает UID проверки и убирает уже обработанные письма: после сбоя очереди
+// письма забираются повторно, но до Ack дошли не все. Письма до skipped пропущены
+// намеренно (MaxEmails), UID без письма (ошибка разбора) обрабатывать нечего
+func (c *Client) track(emails []*models.Email, fetched []uint32, skipped uint32) ([]*models.Email, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastUid = max(c.lastUid, skipped)
+	c.fetched = c.fetched[:0]
+	for _, uid := range fetched {
+		if uid > c.lastUid {
+			c.fetched = append(c.fetched, uid)
+		}
+	}
+	slices.Sort(c.fetched)
+
+	parsed := make(map[uint32]bool, len(emails))
+	var fresh []*models.Email
+	for _, email := range emails {
+		parsed[email.UID] = true
+		if !c.acked[email.UID] {
+			fresh = append(fresh, email)
+		}
+	}
+	for _, uid := range c.fetched {
+		if !parsed[uid] {
+			c.acked[uid] = true
+		}
+	}
+
+	return fresh, c.advanceLocked()
+}
+
+// Ack отмечает письмо обработанным: уведомления по нему уже в очереди.
+// lastUid сдвигается до первого необработанного письма и сохраняется
+func (c *Client) Ack(uid uint32) error {
+	if uid == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if uid <= c.lastUid {
+		return nil
+	}
+	c.acked[uid] = true
+	return c.advanceLocked()
+}
+
+// advanceLocked сдвигает lastUid по обработанным подряд письмам и сохраняет его
+func (c *Client) advanceLocked() error {
+	last := c.lastUid
+	for len(c.fetched) > 0 && c.acked[c.fetched[0]] {
+		c.lastUid = c.fetched[0]
+		delete(c.acked, c.fetched[0])
+		c.fetched = c.fetched[1:]
+	}
+	if c.lastUid == last || c.readOnly {
+		return nil
+	}
+	return c.saveState()
+}
+
+// SearchQuery - критерии поиска писем для повторной обработки.
+// Нулевые поля не ограничивают поиск
+type SearchQuery struct {
+	Since   time.Time // письма с этой даты включительно (IMAP SINCE)
+	Before  time.Time // письма до этой даты, не включая её (IMAP BEFORE)
+	UIDFrom uint32
+	UIDTo   uint32 // 0 - до последнего письма
+}
+
+// Search ищет письма по критериям. Ящик открывается только для чтения,
+// письма не помечаются прочитанными, lastUid и файл состояния не меняются
+func (c *Client) Search(q SearchQuery) ([]*models.Email, error) {
+	if !c.connected.Load() {
+		return nil, fmt.Errorf("клиент не подключен")
+	}
+
+	mailbox, err := c.client.Select(c.config.IMAP.Mailbox, true)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка выбора ящика: %w", err)
+	}
+	if mailbox.Messages == 0 {
+		return []*models.Email{}, nil
+	}
+
+	criteria := imap.NewSearchCriteria()
+	criteria.Since = q.Since
+	criteria.Before = q.Before
+	if q.UIDFrom > 0 || q.UIDTo > 0 {
+		criteria.Uid = new(imap.SeqSet)
+		criteria.Uid.AddRange(max(q.UIDFrom, 1), q.UIDTo)
+	}
+
+	uids, err := c.client.UidSearch(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска писем: %w", err)
+	}
+	if len(uids) == 0 {
+		return []*models.Email{}, nil
+	}
+
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uids...)
+
+	emails, _, err := c.fetch(seqset, true)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Найдено писем по запросу: %d", len(emails))
+	return emails, nil
+}
+
+// fetch загружает письма по UID и возвращает их вместе с UID всех полученных писем,
+// в том числе тех, что не удалось разобрать. peek - не помечать письма прочитанными
+func (c *Client) fetch(seqset *imap.SeqSet, peek bool) ([]*models.Email, []uint32, error) {
 	// Запрашиваем заголовки и тела писем
 	messages := make(chan *imap.Message, 10)
 	done := make(chan error, 1)
 
-	section := &imap.BodySectionName{}
+	section := &imap.BodySectionName{Peek: peek}
 	items := []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, imap.FetchInternalDate, section.FetchItem()}
 
 	go func() {
@@ -172,7 +363,9 @@ func (c *Client) GetNewEmails() ([]*models.Email, error) {
 	}()
 
 	var emails []*models.Email
+	var uids []uint32
 	for msg := range messages {
+		uids = append(uids, msg.Uid)
 		email, err := parseMessage(msg)
 
 		if err != nil {
@@ -181,27 +374,18 @@ func (c *Client) GetNewEmails() ([]*models.Email, error) {
 		}
 
 		emails = append(emails, email)
-		if msg.Uid > c.lastUid {
-			c.lastUid = msg.Uid
-		}
 	}
 
 	if err := <-done; err != nil {
-		return nil, fmt.Errorf("ошибка получения писем: %w", err)
-	}
-
-	if err := c.saveState(); err != nil {
-		return nil, fmt.Errorf("ошибка сохранения состояния: %w", err)
+		return nil, nil, fmt.Errorf("ошибка получения писем: %w", err)
 	}
-
-	log.Printf("Найдено писем: %d", len(emails))
-	return emails, nil
+	return emails, uids, nil
 }
 
 // Close закрывает соединение
 func (c *Client) Close() error {
-	if c.connected {
-		c.connected = false
+	c.lost.Store(false)
+	if c.connected.Swap(false) {
 		return c.client.Logout()
 	}
 	return nil
@@ -209,5 +393,5 @@ func (c *Client) Close() error {
 
 // IsConnected возвращает статус подключения
 func (c *Client) IsConnected() bool {
-	return c.connected
+	return c.connected.Load()
 }
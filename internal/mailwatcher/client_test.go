@@ -0,0 +1,103 @@
+package mailwatcher
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func testClient(t *testing.T) *Client {
+	t.Helper()
+	return &Client{
+		stateFile: filepath.Join(t.TempDir(), "mail_state.json"),
+		acked:     make(map[uint32]bool),
+	}
+}
+
+func testEmails(uids ...uint32) []*models.Email {
+	var emails []*models.Email
+	for _, uid := range uids {
+		emails = append(emails, &models.Email{UID: uid})
+	}
+	return emails
+}
+
+func emailUIDs(emails []*models.Email) []uint32 {
+	var uids []uint32
+	for _, email := range emails {
+		uids = append(uids, email.UID)
+	}
+	return uids
+}
+
+func TestAck(t *testing.T) {
+	c := testClient(t)
+
+	// Письмо 12 не разобралось, обрабатывать его нечего
+	emails, err := c.track(testEmails(11, 13, 14), []uint32{11, 12, 13, 14}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := emailUIDs(emails); !slices.Equal(got, []uint32{11, 13, 14}) {
+		t.Fatalf("unexpected emails: %v", got)
+	}
+	if c.lastUid != 10 {
+		t.Errorf("lastUid moved before ack: %d", c.lastUid)
+	}
+
+	// Уведомления по 11 не попали в очередь, 13 и 14 обработаны: lastUid стоит на месте
+	for _, uid := range []uint32{13, 14} {
+		if err := c.Ack(uid); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if c.lastUid != 10 {
+		t.Errorf("lastUid must wait for unacked email, got: %d", c.lastUid)
+	}
+
+	// Повторная проверка отдаёт только необработанное письмо
+	emails, err = c.track(testEmails(11, 13, 14), []uint32{11, 12, 13, 14}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := emailUIDs(emails); !slices.Equal(got, []uint32{11}) {
+		t.Fatalf("expected only unacked email, got: %v", got)
+	}
+
+	if err := c.Ack(11); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.lastUid != 14 {
+		t.Errorf("expected lastUid 14, got: %d", c.lastUid)
+	}
+
+	// Состояние сохранено на диск
+	restored := testClient(t)
+	restored.stateFile = c.stateFile
+	if err := restored.loadState(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restored.lastUid != 14 {
+		t.Errorf("expected saved lastUid 14, got: %d", restored.lastUid)
+	}
+}
+
+func TestAckReadOnly(t *testing.T) {
+	c := testClient(t)
+	c.readOnly = true
+
+	if _, err := c.track(testEmails(5), []uint32{5}, 4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Ack(5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.lastUid != 5 {
+		t.Errorf("expected lastUid 5, got: %d", c.lastUid)
+	}
+	if err := c.loadState(); err != nil || c.lastUid != 0 {
+		t.Errorf("state must not be saved in read-only mode, got: %d, %v", c.lastUid, err)
+	}
+}
@@ -0,0 +1,113 @@
+package mailwatcher
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/emersion/go-message/mail"
+)
+
+// ParseEML разбирает письмо в формате RFC 5322 (.eml) тем же парсером, что и письма из IMAP
+func ParseEML(r io.Reader) (*models.Email, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения письма: %w", err)
+	}
+
+	mr, err := mail.CreateReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора заголовков: %w", err)
+	}
+	header := mr.Header
+	mr.Close()
+
+	// Поля, которые для IMAP берутся из envelope
+	email := models.NewEmail()
+	email.Size = len(data)
+	email.Subject, _ = header.Subject()
+	email.MessageID, _ = header.MessageID()
+	if date, err := header.Date(); err == nil {
+		email.Date = date
+	}
+	if from, err := header.AddressList("From"); err == nil && len(from) > 0 {
+		email.From = formatMailAddress(from[0])
+	}
+	if to, err := header.AddressList("To"); err == nil {
+		for _, addr := range to {
+			email.To = append(email.To, formatMailAddress(addr))
+		}
+	}
+
+	if err := parseMIMEBody(bytes.NewReader(data), email); err != nil {
+		return email, fmt.Errorf("ошибка парсинга тела: %w", err)
+	}
+	return email, nil
+}
+
+// formatMailAddress форматирует адрес так же, как formatAddress для IMAP
+func formatMailAddress(addr *mail.Address) string {
+	if addr.Name != "" {
+		return fmt.Sprintf("%s <%s>", addr.Name, addr.Address)
+	}
+	return addr.Address
+}
+
+// ParseMbox разбирает почтовый ящик в формате mbox: письма разделены строками "From "
+func ParseMbox(r io.Reader) ([]*models.Email, error) {
+	var emails []*models.Email
+	var current bytes.Buffer
+	started := false
+
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		email, err := ParseEML(bytes.NewReader(current.Bytes()))
+		if err != nil {
+			return fmt.Errorf("письмо %d: %w", len(emails)+1, err)
+		}
+		emails = append(emails, email)
+		current.Reset()
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "From ") {
+			if err := flush(); err != nil {
+				return emails, err
+			}
+			started = true
+			continue
+		}
+		if !started {
+			continue
+		}
+
+		// mboxrd: ">From " в начале строки экранирует "From "
+		if trimmed := strings.TrimLeft(line, ">"); len(trimmed) < len(line) && strings.HasPrefix(trimmed, "From ") {
+			line = line[1:]
+		}
+		current.WriteString(line)
+		current.WriteString("\r\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return emails, fmt.Errorf("ошибка чтения mbox: %w", err)
+	}
+
+	if err := flush(); err != nil {
+		return emails, err
+	}
+	return emails, nil
+}
+
+// IsMbox проверяет, похоже ли содержимое на mbox
+func IsMbox(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("From "))
+}
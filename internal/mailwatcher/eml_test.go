@@ -0,0 +1,67 @@
+package mailwatcher
+
+import (
+	"strings"
+	"testing"
+)
+
+const testEML = "From: =?UTF-8?B?0JzQtdC00L/Rg9C90LrRgg==?= <med@hse.ru>\r\n" +
+	"To: staff@hse.ru\r\n" +
+	"Subject: =?UTF-8?B?0JfQsNC/0LjRgdGMINC90LAg0LzQtdC00L7RgdC80L7RgtGA?=\r\n" +
+	"Message-ID: <a1@hse.ru>\r\n" +
+	"In-Reply-To: <a0@hse.ru>\r\n" +
+	"Date: Wed, 01 Oct 2025 09:30:00 +0300\r\n" +
+	"Content-Type: multipart/alternative; boundary=b1\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Открыта запись\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<a href=\"https://hse.ru/med\">запись</a>\r\n" +
+	"--b1--\r\n"
+
+func TestParseEML(t *testing.T) {
+	email, err := ParseEML(strings.NewReader(testEML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if email.Subject != "Запись на медосмотр" {
+		t.Errorf("incorrect subject, got: '%v'", email.Subject)
+	}
+	if !strings.Contains(email.From, "med@hse.ru") {
+		t.Errorf("incorrect from, got: '%v'", email.From)
+	}
+	if email.MessageID != "a1@hse.ru" {
+		t.Errorf("incorrect message id, got: '%v'", email.MessageID)
+	}
+	if !strings.Contains(email.Body, "Открыта запись") {
+		t.Errorf("incorrect body, got: '%v'", email.Body)
+	}
+	if len(email.Links) != 1 || email.Links[0] != "https://hse.ru/med" {
+		t.Errorf("incorrect links, got: %v", email.Links)
+	}
+	if ids := email.ThreadIDs(); len(ids) != 1 || ids[0] != "a0@hse.ru" {
+		t.Errorf("incorrect thread ids, got: %v", ids)
+	}
+}
+
+func TestParseMbox(t *testing.T) {
+	mbox := "From med@hse.ru Wed Oct  1 09:30:00 2025\n" + testEML +
+		"\nFrom news@hse.ru Wed Oct  1 10:00:00 2025\n" +
+		"From: news@hse.ru\nSubject: Новости\n\n>From the editor\n"
+
+	emails, err := ParseMbox(strings.NewReader(mbox))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(emails) != 2 {
+		t.Fatalf("expected 2 emails, got: %d", len(emails))
+	}
+	if emails[1].Subject != "Новости" || !strings.Contains(emails[1].Body, "From the editor") {
+		t.Errorf("incorrect second email, got: '%v' / '%v'", emails[1].Subject, emails[1].Body)
+	}
+}
@@ -30,6 +30,7 @@ func parseMessage(msg *imap.Message) (*models.Email, error) {
 
 	email := models.NewEmail()
 	email.MessageID = msg.Envelope.MessageId
+	email.UID = msg.Uid
 	email.Subject = msg.Envelope.Subject
 	email.Date = msg.Envelope.Date
 
@@ -126,7 +127,8 @@ func parseMIMEBody(section io.Reader, email *models.Email) error {
 
 		// Сохраняем в зависимости от типа
 		switch {
-		case strings.Contains(contentType, "text/plain"):
+		case contentType == "" || strings.Contains(contentType, "text/plain"):
+			// Без Content-Type по RFC 2045 считается text/plain
 			email.Body = content
 		case strings.Contains(contentType, "text/html"):
 			email.HTML = content
@@ -17,6 +17,23 @@ func NewWatcher(cfg *config.Config) *Watcher {
 	return NewIMAPClient(cfg)
 }
 
+// Pause приостанавливает плановые проверки почты. Внеочередная проверка (CheckNow) работает
+func (w *Watcher) Pause() { w.paused.Store(true) }
+
+// Resume возобновляет плановые проверки почты
+func (w *Watcher) Resume() { w.paused.Store(false) }
+
+// Paused сообщает, приостановлены ли проверки
+func (w *Watcher) Paused() bool { return w.paused.Load() }
+
+// CheckNow запрашивает внеочередную проверку почты, не дожидаясь интервала
+func (w *Watcher) CheckNow() {
+	select {
+	case w.checkCh <- struct{}{}:
+	default:
+	}
+}
+
 // Watch запускает мониторинг почты (go func внутри)
 func (w *Watcher) Watch(ctx context.Context) (<-chan *models.Email, <-chan error) {
 	emailCh := make(chan *models.Email)
@@ -39,27 +56,18 @@ func (w *Watcher) Watch(ctx context.Context) (<-chan *models.Email, <-chan error
 		defer ticker.Stop()
 
 		// Первый просмотр сразу при запуске, чтобы не ждать.
-		emails, err := w.GetNewEmails()
-		if err != nil {
-			errorCh <- fmt.Errorf("ошибка проверки почты: %w", err)
-		} else {
-			for _, email := range emails {
-				emailCh <- email
-			}
-		}
+		w.check(emailCh, errorCh)
 
 		for {
 			select {
 			case <-ticker.C:
-				emails, err := w.GetNewEmails()
-				if err != nil {
-					errorCh <- fmt.Errorf("ошибка проверки почты: %w", err)
+				if w.Paused() {
 					continue
 				}
-
-				for _, email := range emails {
-					emailCh <- email
-				}
+				w.check(emailCh, errorCh)
+			case <-w.checkCh:
+				log.Println("Внеочередная проверка почты")
+				w.check(emailCh, errorCh)
 			case <-ctx.Done():
 				log.Println("Мониторинг почты остановлен")
 				return
@@ -69,3 +77,16 @@ func (w *Watcher) Watch(ctx context.Context) (<-chan *models.Email, <-chan error
 
 	return emailCh, errorCh
 }
+
+// check забирает новые письма и отдаёт их в каналы Watch
+func (w *Watcher) check(emailCh chan<- *models.Email, errorCh chan<- error) {
+	emails, err := w.GetNewEmails()
+	if err != nil {
+		errorCh <- fmt.Errorf("ошибка проверки почты: %w", err)
+		return
+	}
+
+	for _, email := range emails {
+		emailCh <- email
+	}
+}
@@ -0,0 +1,33 @@
+package metrics
+
+// Default - метрики монитора, которые отдаёт /metrics
+var Default = NewRegistry()
+
+// Корзины задержек: от долей секунды для IMAP до суток для доставки
+var (
+	fetchBuckets    = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+	deliveryBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 4 * 3600, 12 * 3600, 24 * 3600}
+)
+
+var (
+	EmailsFetched = Default.NewCounterVec("catchletter_emails_fetched_total",
+		"Писем получено из ящика", "mailbox")
+	IMAPFetchDuration = Default.NewHistogramVec("catchletter_imap_fetch_duration_seconds",
+		"Длительность проверки ящика", fetchBuckets, "mailbox")
+	IMAPReconnects = Default.NewCounterVec("catchletter_imap_reconnects_total",
+		"Переподключений к IMAP серверу")
+	LastCheckSuccess = Default.NewGaugeVec("catchletter_last_check_success_timestamp_seconds",
+		"Время последней успешной проверки ящика", "mailbox")
+
+	RuleEvaluations = Default.NewCounterVec("catchletter_rule_evaluations_total",
+		"Писем проверено правилом", "rule")
+	RuleMatches = Default.NewCounterVec("catchletter_rule_matches_total",
+		"Срабатываний правила", "rule")
+	Alerts = Default.NewCounterVec("catchletter_alerts_total",
+		"Алертов по уровням", "level")
+
+	Notifications = Default.NewCounterVec("catchletter_notifications_total",
+		"Попыток отправки уведомлений по нотификаторам, result: sent или failed", "notifier", "result")
+	DeliveryLatency = Default.NewHistogramVec("catchletter_delivery_latency_seconds",
+		"Задержка от даты письма до доставки уведомления", deliveryBuckets, "notifier")
+)
@@ -0,0 +1,271 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Registry - набор метрик, которые отдаются в текстовом формате Prometheus
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// NewRegistry создаёт пустой набор метрик
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write пишет все метрики в текстовом формате Prometheus
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler возвращает HTTP обработчик для /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// series - общая часть метрик с метками: значения по набору значений меток
+type series[T any] struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*T
+	keys   map[string][]string
+}
+
+func newSeries[T any](name, help, kind string, labels []string) *series[T] {
+	return &series[T]{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		values: make(map[string]*T),
+		keys:   make(map[string][]string),
+	}
+}
+
+// withLocked находит или создаёт значение для набора меток и вызывает fn под блокировкой
+func (s *series[T]) withLocked(labelValues []string, init func() *T, fn func(*T)) {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: %s ожидает %d меток, получено %d", s.name, len(s.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		value = init()
+		s.values[key] = value
+		s.keys[key] = append([]string(nil), labelValues...)
+	}
+	fn(value)
+}
+
+// each обходит значения в порядке меток, чтобы вывод был стабильным
+func (s *series[T]) each(w io.Writer, fn func(labels string, value *T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", s.name, s.help, s.name, s.kind)
+
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fn(formatLabels(s.labels, s.keys[key]), s.values[key])
+	}
+}
+
+// CounterVec - монотонно растущий счётчик с метками
+type CounterVec struct {
+	s *series[float64]
+}
+
+// NewCounterVec создаёт и регистрирует счётчик
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{s: newSeries[float64](name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// Inc увеличивает счётчик на 1
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add увеличивает счётчик на v
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	c.s.withLocked(labelValues, func() *float64 { return new(float64) }, func(value *float64) {
+		*value += v
+	})
+}
+
+// Value возвращает текущее значение счётчика, 0 если его ещё не было
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+
+	if value, ok := c.s.values[strings.Join(labelValues, "\xff")]; ok {
+		return *value
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.s.each(w, func(labels string, value *float64) {
+		fmt.Fprintf(w, "%s%s %s\n", c.s.name, labels, formatFloat(*value))
+	})
+}
+
+// GaugeVec - значение, которое может как расти, так и уменьшаться
+type GaugeVec struct {
+	s *series[float64]
+}
+
+// NewGaugeVec создаёт и регистрирует gauge
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{s: newSeries[float64](name, help, "gauge", labels)}
+	r.register(g)
+	return g
+}
+
+// Set задаёт значение
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.s.withLocked(labelValues, func() *float64 { return new(float64) }, func(value *float64) {
+		*value = v
+	})
+}
+
+// SetTime задаёт значение как Unix-время в секундах
+func (g *GaugeVec) SetTime(t time.Time, labelValues ...string) {
+	g.Set(float64(t.UnixNano())/1e9, labelValues...)
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.s.each(w, func(labels string, value *float64) {
+		fmt.Fprintf(w, "%s%s %s\n", g.s.name, labels, formatFloat(*value))
+	})
+}
+
+// HistogramVec - распределение значений по корзинам
+type HistogramVec struct {
+	s       *series[histogram]
+	buckets []float64
+}
+
+type histogram struct {
+	counts []uint64 // по корзинам, не накопительно
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec создаёт и регистрирует гистограмму с верхними границами корзин buckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		s:       newSeries[histogram](name, help, "histogram", labels),
+		buckets: append([]float64(nil), buckets...),
+	}
+	sort.Float64s(h.buckets)
+	r.register(h)
+	return h
+}
+
+// Observe добавляет значение
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	init := func() *histogram { return &histogram{counts: make([]uint64, len(h.buckets))} }
+	h.s.withLocked(labelValues, init, func(value *histogram) {
+		value.sum += v
+		value.count++
+		if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+			value.counts[i]++
+		}
+	})
+}
+
+// ObserveSince добавляет время, прошедшее с start, в секундах
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.s.each(w, func(labels string, value *histogram) {
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += value.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.s.name, withLabel(labels, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.s.name, withLabel(labels, "le", "+Inf"), value.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.s.name, labels, formatFloat(value.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.s.name, labels, value.count)
+	})
+}
+
+// formatLabels собирает {name="value",...}, пустая строка без меток
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withLabel добавляет метку к уже собранному набору
+func withLabel(labels, name, value string) string {
+	pair := name + `="` + labelEscaper.Replace(value) + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+// labelEscaper экранирует значение метки по текстовому формату Prometheus
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	matches := r.NewCounterVec("test_matches_total", "Срабатывания", "rule")
+	reconnects := r.NewCounterVec("test_reconnects_total", "Переподключения")
+	latency := r.NewHistogramVec("test_latency_seconds", "Задержка", []float64{1, 5}, "notifier")
+
+	matches.Inc("Медосмотр")
+	matches.Add(2, `Правило "с кавычками"`)
+	reconnects.Inc()
+	latency.Observe(0.5, "telegram")
+	latency.Observe(3, "telegram")
+	latency.Observe(10, "telegram")
+
+	var out strings.Builder
+	r.Write(&out)
+
+	expected := `# HELP test_matches_total Срабатывания
+# TYPE test_matches_total counter
+test_matches_total{rule="Медосмотр"} 1
+test_matches_total{rule="Правило \"с кавычками\""} 2
+# HELP test_reconnects_total Переподключения
+# TYPE test_reconnects_total counter
+test_reconnects_total 1
+# HELP test_latency_seconds Задержка
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{notifier="telegram",le="1"} 1
+test_latency_seconds_bucket{notifier="telegram",le="5"} 2
+test_latency_seconds_bucket{notifier="telegram",le="+Inf"} 3
+test_latency_seconds_sum{notifier="telegram"} 13.5
+test_latency_seconds_count{notifier="telegram"} 3
+`
+	if out.String() != expected {
+		t.Errorf("incorrect output, expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	if got := matches.Value("Медосмотр"); got != 1 {
+		t.Errorf("expected value 1, got: %v", got)
+	}
+}
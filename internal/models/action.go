@@ -0,0 +1,61 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Action - действие правила: тип нотификатора и, при необходимости, именованный получатель
+// из блока targets этого нотификатора.
+//
+// В YAML записывается строкой ("telegram", "telegram:students")
+// или объектом ({type: telegram, target: students}).
+type Action struct {
+	Type   ActionType `yaml:"type" json:"type"`
+	Target string     `yaml:"target,omitempty" json:"target,omitempty"`
+}
+
+// ParseAction разбирает действие из строки вида "тип" или "тип:получатель"
+func ParseAction(s string) Action {
+	actionType, target, _ := strings.Cut(strings.TrimSpace(s), ":")
+	return Action{
+		Type:   ActionType(strings.TrimSpace(actionType)),
+		Target: strings.TrimSpace(target),
+	}
+}
+
+// String возвращает действие в виде "тип" или "тип:получатель"
+func (a Action) String() string {
+	if a.Target == "" {
+		return string(a.Type)
+	}
+	return fmt.Sprintf("%s:%s", a.Type, a.Target)
+}
+
+// UnmarshalYAML поддерживает обе формы записи действия
+func (a *Action) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*a = ParseAction(node.Value)
+		return nil
+	}
+
+	// Отдельный тип, чтобы не уйти в рекурсию
+	type plain Action
+	var p plain
+	if err := node.Decode(&p); err != nil {
+		return err
+	}
+	*a = Action(p)
+	return nil
+}
+
+// MarshalYAML записывает действие без получателя короткой строкой
+func (a Action) MarshalYAML() (any, error) {
+	if a.Target == "" {
+		return string(a.Type), nil
+	}
+	type plain Action
+	return plain(a), nil
+}
@@ -2,19 +2,33 @@ package models
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"go.yaml.in/yaml/v3"
 )
 
 type Alert struct {
-	ID        ID         `json:"id"`
-	Email     *Email     `json:"email"`
-	Rule      *Rule      `json:"rule"`
-	Score     int        `json:"score"`
-	Level     AlertLevel `json:"level"`
-	Reason    string     `json:"reason"`
-	Message   string     `json:"message"`
-	CreatedAt time.Time  `json:"created_at"`
-	Processed bool       `json:"processed"`
+	ID    ID         `json:"id"`
+	Email *Email     `json:"email"`
+	Rule  *Rule      `json:"rule"`
+	Score int        `json:"score"`
+	Level AlertLevel `json:"level"`
+	// LevelReason - почему выбран такой уровень
+	LevelReason string    `json:"level_reason,omitempty"`
+	Reason      string    `json:"reason"`
+	Message     string    `json:"message"` // текст по встроенному шаблону, см. notifier.Message
+	CreatedAt   time.Time `json:"created_at"`
+	Processed   bool      `json:"processed"`
+	// NearMiss - письму не хватило баллов до MinScore, алерт идёт только в дайджест
+	NearMiss bool `json:"near_miss,omitempty"`
+	// ReplacesID - повтор ранее отправленного алерта: нотификатор обновит
+	// старое сообщение вместо нового, если умеет. Repeats - сколько было повторов
+	ReplacesID ID  `json:"replaces_id,omitempty"`
+	Repeats    int `json:"repeats,omitempty"`
+	// Group - алерты, объединённые в сводку. Для обычного алерта пустой
+	Group []*Alert `json:"group,omitempty"`
 }
 
 type AlertLevel int
@@ -26,6 +40,48 @@ const (
 	AlertCritical AlertLevel = 4
 )
 
+// String возвращает название уровня важности
+func (l AlertLevel) String() string {
+	switch l {
+	case AlertLow:
+		return "low"
+	case AlertMedium:
+		return "medium"
+	case AlertHigh:
+		return "high"
+	case AlertCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseAlertLevel разбирает уровень по названию (low, medium, high, critical) или числу 1-4
+func ParseAlertLevel(s string) (AlertLevel, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for level := AlertLow; level <= AlertCritical; level++ {
+		if s == level.String() || s == strconv.Itoa(int(level)) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("неизвестный уровень важности: %s", s)
+}
+
+// UnmarshalYAML позволяет писать уровень в конфиге словом: min_level: high
+func (l *AlertLevel) UnmarshalYAML(value *yaml.Node) error {
+	level, err := ParseAlertLevel(value.Value)
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// MarshalYAML записывает уровень словом
+func (l AlertLevel) MarshalYAML() (any, error) {
+	return l.String(), nil
+}
+
 // NewAlert создает новые Alert
 func NewAlert(e *Email, r *Rule, score int, reason string) *Alert {
 	alert := Alert{
@@ -40,43 +96,78 @@ func NewAlert(e *Email, r *Rule, score int, reason string) *Alert {
 
 	// Определяем уровень важности на основе баллов
 	alert.calculateLevel()
-	alert.generateMessage()
 
 	return &alert
 }
 
-// calculateLevel определяет уровень важности
+// calculateLevel определяет уровень важности по порогам правила
 func (a *Alert) calculateLevel() {
-	switch {
-	case a.Score >= a.Rule.MinScore+a.Rule.MinScore/2:
-		a.Level = AlertCritical
-	case a.Score >= a.Rule.MinScore+a.Rule.MinScore/3:
-		a.Level = AlertHigh
-	case a.Score >= a.Rule.MinScore:
-		a.Level = AlertMedium
-	default:
-		a.Level = AlertLow
+	a.Level, a.LevelReason = a.Rule.GetThresholds().Level(a.Score)
+}
+
+// SetLevel задаёт уровень важности с объяснением
+func (a *Alert) SetLevel(level AlertLevel, reason string) {
+	a.Level = level
+	a.LevelReason = reason
+}
+
+// NewSummaryAlert создает сводный алерт из нескольких алертов.
+// title становится темой сводки, rule может быть nil, если в сводке разные правила
+func NewSummaryAlert(title string, rule *Rule, alerts []*Alert) *Alert {
+	summary := Alert{
+		ID:        GenerateID(),
+		Rule:      rule,
+		Group:     alerts,
+		CreatedAt: time.Now(),
+	}
+	if summary.Rule == nil {
+		summary.Rule = &Rule{Name: title}
 	}
+
+	// Тема сводки и ссылки из писем, чтобы шаблоны одиночного алерта тоже работали
+	email := NewEmail()
+	email.Subject = title
+	email.Date = summary.CreatedAt
+
+	for _, alert := range alerts {
+		summary.Level = max(summary.Level, alert.Level)
+		summary.Score = max(summary.Score, alert.Score)
+		if alert.Email == nil {
+			continue
+		}
+		if len(alert.Email.Links) > 0 {
+			email.Links = append(email.Links, alert.Email.Links[0])
+		}
+	}
+
+	summary.Email = email
+	return &summary
 }
 
-// generateMessage генерирует сообщение для уведомления
-func (a *Alert) generateMessage() {
-	levelNames := map[AlertLevel]string{
-		AlertLow:      "🔔",
-		AlertMedium:   "⚠️",
-		AlertHigh:     "🚨",
-		AlertCritical: "🔥",
+// NewSystemAlert создает алерт о работе самой системы, не связанный с письмом.
+// title становится темой и именем правила, чтобы шаблоны одиночного алерта тоже работали
+func NewSystemAlert(title, text string) *Alert {
+	email := NewEmail()
+	email.Subject = title
+
+	return &Alert{
+		ID:        GenerateID(),
+		Email:     email,
+		Rule:      &Rule{Name: title},
+		Level:     AlertHigh,
+		Reason:    text,
+		CreatedAt: email.Date,
 	}
+}
+
+// IsRepeat проверяет, является ли алерт повтором ранее отправленного
+func (a *Alert) IsRepeat() bool {
+	return a.ReplacesID != ""
+}
 
-	a.Message = fmt.Sprintf("%s %s\nТема: %s\nОт: %s\nБалл: %d/%d\nПричина: %s",
-		levelNames[a.Level],
-		a.Rule.Name,
-		a.Email.Subject,
-		a.Email.From,
-		a.Score,
-		a.Rule.MinScore,
-		a.Reason,
-	)
+// IsSummary проверяет, является ли алерт сводкой
+func (a *Alert) IsSummary() bool {
+	return len(a.Group) > 0
 }
 
 // MarkProcessed отмечает алерт как обработанный
@@ -16,13 +16,29 @@ const (
 	ConditionHeader  ConditionType = "header"
 )
 
+// ConditionTypes - все типы условий
+var ConditionTypes = []ConditionType{ConditionFrom, ConditionSubject, ConditionBody, ConditionHeader}
+
 type ActionType string
 
 const (
-	ActionNotifyTelegram ActionType = "telegram"
-	ActionNotifySms      ActionType = "sms"
+	ActionNotifyTelegram   ActionType = "telegram"
+	ActionNotifySlack      ActionType = "slack"
+	ActionNotifyMattermost ActionType = "mattermost"
+	ActionNotifyDiscord    ActionType = "discord"
+	ActionNotifyMatrix     ActionType = "matrix"
+	ActionNotifyNtfy       ActionType = "ntfy"
+	ActionNotifyGotify     ActionType = "gotify"
+	ActionNotifyDesktop    ActionType = "desktop"
+	ActionNotifySms        ActionType = "sms"
 )
 
+// ActionTypes - все типы действий
+var ActionTypes = []ActionType{
+	ActionNotifyTelegram, ActionNotifySlack, ActionNotifyMattermost, ActionNotifyDiscord,
+	ActionNotifyMatrix, ActionNotifyNtfy, ActionNotifyGotify, ActionNotifyDesktop, ActionNotifySms,
+}
+
 type Operator string
 
 const (
@@ -33,6 +49,9 @@ const (
 	OperatorMatches    Operator = "matches"
 )
 
+// Operators - все операторы условий
+var Operators = []Operator{OperatorContains, OperatorEquals, OperatorStartsWith, OperatorEndsWith, OperatorMatches}
+
 // GenerateID - генерирует случайный ID
 func GenerateID() ID {
 	b := make([]byte, 16)
@@ -8,6 +8,7 @@ import (
 type Email struct {
 	ID        ID
 	MessageID string            // ID письма из IMAP
+	UID       uint32            // UID письма в ящике, 0 - письмо не из IMAP
 	From      string            // Отправитель
 	To        []string          // Получатели
 	Subject   string            // Тема письма
@@ -51,3 +52,34 @@ func (e *Email) HasLink(pattern string) bool {
 	}
 	return false
 }
+
+// Header возвращает заголовок письма без учёта регистра имени
+func (e *Email) Header(name string) string {
+	if value, ok := e.Headers[name]; ok {
+		return value
+	}
+	for key, value := range e.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// ThreadIDs возвращает Message-ID писем, на которые отвечает это письмо (In-Reply-To и References)
+func (e *Email) ThreadIDs() []string {
+	var ids []string
+	for _, header := range []string{"In-Reply-To", "References"} {
+		for _, field := range strings.Fields(e.Header(header)) {
+			if id := NormalizeMessageID(field); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// NormalizeMessageID приводит Message-ID к виду без угловых скобок и в нижнем регистре
+func NormalizeMessageID(id string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(id), "<>,"))
+}
@@ -1,6 +1,10 @@
 package models
 
-import "testing"
+import (
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
 
 func TestExtractDomain(t *testing.T) {
 	tests := []struct {
@@ -58,3 +62,55 @@ func TestHasLink(t *testing.T) {
 		})
 	}
 }
+
+func TestActionUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected Action
+	}{
+		{
+			name:     "Только тип",
+			data:     `telegram`,
+			expected: Action{Type: ActionNotifyTelegram},
+		},
+		{
+			name:     "Тип и получатель строкой",
+			data:     `"telegram:students"`,
+			expected: Action{Type: ActionNotifyTelegram, Target: "students"},
+		},
+		{
+			name:     "Объект",
+			data:     `{type: slack, target: hr}`,
+			expected: Action{Type: ActionNotifySlack, Target: "hr"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var action Action
+			if err := yaml.Unmarshal([]byte(tt.data), &action); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if action != tt.expected {
+				t.Errorf("incorrect result, expected: '%v', got: '%v'", tt.expected, action)
+			}
+		})
+	}
+}
+
+func TestAlertLevelUnmarshalYAML(t *testing.T) {
+	var cfg struct {
+		Level AlertLevel `yaml:"level"`
+	}
+
+	if err := yaml.Unmarshal([]byte(`level: High`), &cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Level != AlertHigh {
+		t.Errorf("incorrect level, expected: '%v', got: '%v'", AlertHigh, cfg.Level)
+	}
+
+	if err := yaml.Unmarshal([]byte(`level: urgent`), &cfg); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -1,13 +1,76 @@
 package models
 
+import "fmt"
+
 type Rule struct {
 	ID         ID           `yaml:"id" json:"id"`
 	Name       string       `yaml:"name" json:"name"`
 	Enabled    bool         `yaml:"enabled" json:"enabled"`
 	Conditions []Condition  `yaml:"conditions" json:"conditions"`
-	Actions    []ActionType `yaml:"actions" json:"actions"`
+	Actions    []Action     `yaml:"actions" json:"actions"`
 	Priority   int          `yaml:"priority" json:"priority"`
 	MinScore   int          `yaml:"min_score" json:"min_score"`
+	// Templates - шаблоны сообщений для отдельных нотификаторов (ключ - тип действия)
+	Templates map[string]string `yaml:"templates,omitempty" json:"templates,omitempty"`
+	// QuietHours - переопределение тихих часов для правила
+	QuietHours *RuleQuietHours `yaml:"quiet_hours,omitempty" json:"quiet_hours,omitempty"`
+	// Levels - пороги уровней важности для правила вместо общих
+	Levels *LevelThresholds `yaml:"levels,omitempty" json:"levels,omitempty"`
+}
+
+// LevelThresholds - с какого балла начинается уровень важности. Ниже medium - low
+type LevelThresholds struct {
+	Medium   int `yaml:"medium" json:"medium"`
+	High     int `yaml:"high" json:"high"`
+	Critical int `yaml:"critical" json:"critical"`
+}
+
+// DefaultThresholds - пороги по умолчанию: MinScore, +1/3 и +1/2 от него
+func DefaultThresholds(minScore int) LevelThresholds {
+	return LevelThresholds{
+		Medium:   minScore,
+		High:     minScore + minScore/3,
+		Critical: minScore + minScore/2,
+	}
+}
+
+// Level определяет уровень по баллам и объясняет выбор
+func (t LevelThresholds) Level(score int) (AlertLevel, string) {
+	switch {
+	case score >= t.Critical:
+		return AlertCritical, fmt.Sprintf("%d ≥ %d (порог critical)", score, t.Critical)
+	case score >= t.High:
+		return AlertHigh, fmt.Sprintf("%d ≥ %d (порог high)", score, t.High)
+	case score >= t.Medium:
+		return AlertMedium, fmt.Sprintf("%d ≥ %d (порог medium)", score, t.Medium)
+	default:
+		return AlertLow, fmt.Sprintf("%d < %d (порог medium)", score, t.Medium)
+	}
+}
+
+// GetThresholds возвращает пороги правила или пороги по умолчанию от MinScore
+func (r *Rule) GetThresholds() LevelThresholds {
+	if r.Levels != nil {
+		return *r.Levels
+	}
+	return DefaultThresholds(r.MinScore)
+}
+
+// MaxScore возвращает максимально возможные баллы правила - сумму положительных весов
+func (r *Rule) MaxScore() int {
+	var total int
+	for _, cond := range r.Conditions {
+		total += max(cond.Weight, 0)
+	}
+	return total
+}
+
+// RuleQuietHours - настройки тихих часов для отдельного правила
+type RuleQuietHours struct {
+	// MinLevel - порог мгновенной доставки вместо общего
+	MinLevel AlertLevel `yaml:"min_level,omitempty" json:"min_level,omitempty"`
+	// Ignore - алерты правила доставляются сразу, тихие часы не учитываются
+	Ignore bool `yaml:"ignore,omitempty" json:"ignore,omitempty"`
 }
 
 // Condition - условие для правила
@@ -26,7 +89,7 @@ func NewRule(name string) *Rule {
 		Name:       name,
 		Enabled:    true,
 		Conditions: make([]Condition, 0),
-		Actions:    make([]ActionType, 0),
+		Actions:    make([]Action, 0),
 		Priority:   50,
 		MinScore:   60,
 	}
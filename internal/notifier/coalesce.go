@@ -0,0 +1,59 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/outbox"
+)
+
+// Группы отложенных записей очереди (outbox.Deferral): придержанные на тихие часы
+// уходят одной утренней сводкой, отложенные лимитом - сводкой по правилу
+const (
+	GroupQuiet       = "quiet"
+	groupLimitPrefix = "limit:"
+)
+
+// MergeDeferred объединяет отложенные алерты группы в сводку (outbox.MergeFunc)
+func MergeDeferred(group string, alerts []*models.Alert) *models.Alert {
+	if len(alerts) == 1 {
+		return alerts[0]
+	}
+	if rule, ok := strings.CutPrefix(group, groupLimitPrefix); ok {
+		title := fmt.Sprintf("Ещё %d алертов по правилу %s", len(alerts), rule)
+		return models.NewSummaryAlert(title, alerts[0].Rule, alerts)
+	}
+	title := fmt.Sprintf("Утренняя сводка: %d алертов", len(alerts))
+	return models.NewSummaryAlert(title, nil, alerts)
+}
+
+// allow проверяет лимит нотификатора. Без настроенного лимита всегда true
+func (m *Manager) allow(actionType models.ActionType, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	limiter, exists := m.limiters[actionType]
+	return !exists || limiter.allow(now)
+}
+
+// deferAlert откладывает алерт до появления свободного токена. Алерты одного правила,
+// отложенные к этому моменту, уйдут одной сводкой
+func (m *Manager) deferAlert(action models.Action, alert *models.Alert, now time.Time) error {
+	m.mu.Lock()
+	until := m.limiters[action.Type].next(now)
+	m.mu.Unlock()
+
+	log.Printf("Лимит %s исчерпан, алерт отложен до %s: %s", action.Type, until.Format("15:04:05"), ruleName(alert))
+	return outbox.Defer(until, groupLimitPrefix+ruleName(alert))
+}
+
+// ruleName возвращает имя правила алерта для логов и групп
+func ruleName(alert *models.Alert) string {
+	if alert.Rule == nil {
+		return ""
+	}
+	return alert.Rule.Name
+}
@@ -0,0 +1,227 @@
+package notifier
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	desktopDest      = "org.freedesktop.Notifications"
+	desktopPath      = "/org/freedesktop/Notifications"
+	desktopInterface = "org.freedesktop.Notifications"
+
+	// desktopOpenAction - ключ кнопки, открывающей первую ссылку письма
+	desktopOpenAction = "open"
+)
+
+// busObject - объект сервера уведомлений на шине; в тестах подменяется фейком
+type busObject interface {
+	Call(method string, flags dbus.Flags, args ...interface{}) *dbus.Call
+}
+
+// DesktopNotifier показывает всплывающие уведомления через
+// org.freedesktop.Notifications на сессионной шине D-Bus
+type DesktopNotifier struct {
+	BaseNotifier
+	conn     *dbus.Conn
+	bus      busObject
+	open     func(link string) error // открывает ссылку по кнопке уведомления
+	appName  string
+	timeout  int32
+	renderer *Renderer
+	enabled  bool
+
+	mu    sync.Mutex
+	links map[uint32]string // ID уведомления -> ссылка для кнопки
+}
+
+func NewDesktop(cfg *config.DesktopConfig) (*DesktopNotifier, error) {
+	notifier := &DesktopNotifier{
+		BaseNotifier: BaseNotifier{name: "desktop"},
+		enabled:      false,
+		links:        make(map[uint32]string),
+		open:         openLink,
+	}
+	if cfg == nil || !cfg.Enabled {
+		return notifier, nil
+	}
+
+	renderer, err := NewRenderer("desktop", cfg.Template, defaultPlainTemplate, FormatPlain)
+	if err != nil {
+		return nil, err
+	}
+	notifier.renderer = renderer
+
+	// Без сессионной шины (сервер, ssh) нотификатор просто недоступен
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		log.Printf("Сессионная шина D-Bus недоступна: %v", err)
+		return notifier, nil
+	}
+
+	notifier.conn = conn
+	notifier.bus = conn.Object(desktopDest, desktopPath)
+	notifier.appName = cfg.AppName
+	if notifier.appName == "" {
+		notifier.appName = "CatchAnImportantLetter"
+	}
+	notifier.timeout = -1 // по умолчанию решает сервер уведомлений
+	if cfg.TimeoutSeconds > 0 {
+		notifier.timeout = int32(cfg.TimeoutSeconds * 1000)
+	}
+
+	if err := notifier.listenActions(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ошибка подписки на сигналы D-Bus: %w", err)
+	}
+
+	notifier.enabled = true
+	log.Printf("Desktop нотификатор инициализирован")
+	return notifier, nil
+}
+
+// Send показывает всплывающее уведомление
+func (d *DesktopNotifier) Send(alert *models.Alert) error {
+	if !d.IsAvailable() {
+		return fmt.Errorf("desktop нотификатор отключен")
+	}
+
+	text, err := d.renderer.Render(alert)
+	if err != nil {
+		return err
+	}
+
+	// Кнопки передаются парами: ключ действия, подпись
+	var actions []string
+	link := firstLink(alert)
+	if link != "" {
+		actions = []string{desktopOpenAction, "Открыть ссылку"}
+	}
+
+	hints := map[string]dbus.Variant{
+		"urgency": dbus.MakeVariant(desktopUrgency(alert.Level)),
+	}
+
+	// Сервер уведомлений может интерпретировать разметку, поэтому экранируем
+	var id uint32
+	err = d.bus.Call(desktopInterface+".Notify", 0,
+		d.appName,
+		uint32(0),
+		"mail-unread",
+		alertTitle(alert),
+		escapeHTML(text),
+		actions,
+		hints,
+		d.timeout,
+	).Store(&id)
+	if err != nil {
+		return fmt.Errorf("ошибка отправки desktop уведомления: %w", err)
+	}
+
+	if link != "" {
+		d.mu.Lock()
+		d.links[id] = link
+		d.mu.Unlock()
+	}
+
+	log.Printf("Desktop уведомление показано: %s", alert.Rule.Name)
+	return nil
+}
+
+// IsAvailable проверяет доступность нотификатора
+func (d *DesktopNotifier) IsAvailable() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.enabled
+}
+
+var _ io.Closer = (*DesktopNotifier)(nil)
+
+// Close закрывает соединение с шиной, после него нотификатор недоступен.
+// Канал сигналов закрывается вместе с соединением, и горутина listenActions завершается
+func (d *DesktopNotifier) Close() error {
+	d.mu.Lock()
+	conn := d.conn
+	d.conn = nil
+	d.enabled = false
+	d.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// listenActions подписывается на нажатия кнопок и закрытие уведомлений
+func (d *DesktopNotifier) listenActions() error {
+	if err := d.conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(desktopPath),
+		dbus.WithMatchInterface(desktopInterface),
+	); err != nil {
+		return err
+	}
+
+	signals := make(chan *dbus.Signal, 10)
+	d.conn.Signal(signals)
+
+	go func() {
+		for signal := range signals {
+			d.handleSignal(signal)
+		}
+	}()
+
+	return nil
+}
+
+// handleSignal открывает ссылку по нажатию кнопки и забывает закрытые уведомления
+func (d *DesktopNotifier) handleSignal(signal *dbus.Signal) {
+	if len(signal.Body) < 2 {
+		return
+	}
+	id, ok := signal.Body[0].(uint32)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	link := d.links[id]
+	d.mu.Unlock()
+
+	switch signal.Name {
+	case desktopInterface + ".ActionInvoked":
+		if action, _ := signal.Body[1].(string); action == desktopOpenAction && link != "" {
+			if err := d.open(link); err != nil {
+				log.Printf("Не удалось открыть ссылку %s: %v", link, err)
+			}
+		}
+
+	case desktopInterface + ".NotificationClosed":
+		d.mu.Lock()
+		delete(d.links, id)
+		d.mu.Unlock()
+	}
+}
+
+// openLink открывает ссылку в браузере по умолчанию
+func openLink(link string) error {
+	return exec.Command("xdg-open", link).Start()
+}
+
+// desktopUrgency переводит уровень важности в urgency спецификации (0 - low, 1 - normal, 2 - critical)
+func desktopUrgency(level models.AlertLevel) byte {
+	switch level {
+	case models.AlertCritical:
+		return 2
+	case models.AlertHigh, models.AlertMedium:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,134 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeBus запоминает аргументы последнего вызова и возвращает заданный ID уведомления
+type fakeBus struct {
+	method string
+	args   []interface{}
+	id     uint32
+}
+
+func (b *fakeBus) Call(method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	b.method = method
+	b.args = args
+	return &dbus.Call{Body: []interface{}{b.id}}
+}
+
+func testDesktop(t *testing.T, bus busObject) *DesktopNotifier {
+	t.Helper()
+	renderer, err := NewRenderer("desktop", "", defaultPlainTemplate, FormatPlain)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &DesktopNotifier{
+		BaseNotifier: BaseNotifier{name: "desktop"},
+		bus:          bus,
+		open:         openLink,
+		appName:      "test",
+		timeout:      -1,
+		renderer:     renderer,
+		enabled:      true,
+		links:        make(map[uint32]string),
+	}
+}
+
+func TestDesktopSend(t *testing.T) {
+	bus := &fakeBus{id: 7}
+	desktop := testDesktop(t, bus)
+
+	if err := desktop.Send(testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if bus.method != desktopInterface+".Notify" {
+		t.Errorf("incorrect method, got: %s", bus.method)
+	}
+	if len(bus.args) != 8 {
+		t.Fatalf("expected 8 arguments, got: %d", len(bus.args))
+	}
+	if bus.args[0] != "test" {
+		t.Errorf("incorrect app name, got: %v", bus.args[0])
+	}
+	if body := bus.args[4].(string); !strings.Contains(body, "Запись &lt;на&gt; медосмотр") {
+		t.Errorf("body is not escaped: %s", body)
+	}
+	actions := bus.args[5].([]string)
+	if len(actions) != 2 || actions[0] != desktopOpenAction {
+		t.Errorf("expected open action, got: %v", actions)
+	}
+	hints := bus.args[6].(map[string]dbus.Variant)
+	if urgency := hints["urgency"].Value(); urgency != byte(2) {
+		t.Errorf("expected critical urgency, got: %v", urgency)
+	}
+	if desktop.links[7] != "https://hse.ru/med" {
+		t.Errorf("link is not remembered, got: %v", desktop.links)
+	}
+}
+
+func TestDesktopSignals(t *testing.T) {
+	desktop := testDesktop(t, &fakeBus{id: 7})
+	var opened []string
+	desktop.open = func(link string) error {
+		opened = append(opened, link)
+		return nil
+	}
+	if err := desktop.Send(testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	desktop.handleSignal(&dbus.Signal{Name: desktopInterface + ".ActionInvoked", Body: []interface{}{uint32(7), "other"}})
+	desktop.handleSignal(&dbus.Signal{Name: desktopInterface + ".ActionInvoked", Body: []interface{}{uint32(8), desktopOpenAction}})
+	desktop.handleSignal(&dbus.Signal{Name: desktopInterface + ".ActionInvoked", Body: []interface{}{uint32(7), desktopOpenAction}})
+	if len(opened) != 1 || opened[0] != "https://hse.ru/med" {
+		t.Errorf("expected link to be opened once, got: %v", opened)
+	}
+
+	desktop.handleSignal(&dbus.Signal{Name: desktopInterface + ".NotificationClosed", Body: []interface{}{uint32(7), uint32(2)}})
+	if len(desktop.links) != 0 {
+		t.Errorf("closed notification is not forgotten: %v", desktop.links)
+	}
+}
+
+func TestDesktopClose(t *testing.T) {
+	desktop := testDesktop(t, &fakeBus{})
+
+	if err := desktop.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if desktop.IsAvailable() {
+		t.Error("expected notifier to be unavailable after Close")
+	}
+	if err := desktop.Send(testAlert()); err == nil {
+		t.Error("expected error after Close")
+	}
+	// Повторное закрытие безопасно
+	if err := desktop.Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDesktopUrgency(t *testing.T) {
+	tests := []struct {
+		level    models.AlertLevel
+		expected byte
+	}{
+		{models.AlertLow, 0},
+		{models.AlertMedium, 1},
+		{models.AlertHigh, 1},
+		{models.AlertCritical, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			if got := desktopUrgency(tt.level); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// Ограничения Discord на размер embed
+const (
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+)
+
+type DiscordNotifier struct {
+	BaseNotifier
+	client     *http.Client
+	webhookURL string
+	username   string
+	targets    map[string]config.WebhookTarget
+	renderer   *Renderer
+	enabled    bool
+}
+
+type discordMessage struct {
+	Username string         `json:"username,omitempty"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+// discordEmbed - пустые строки Discord отклоняет с 400, поэтому текстовые поля omitempty
+type discordEmbed struct {
+	Title       string `json:"title,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Color       int    `json:"color"`
+	Description string `json:"description,omitempty"`
+	Timestamp   string `json:"timestamp,omitempty"`
+}
+
+func NewDiscord(cfg *config.DiscordConfig) (*DiscordNotifier, error) {
+	if cfg == nil || !cfg.Enabled || cfg.WebhookURL == "" {
+		return &DiscordNotifier{
+			BaseNotifier: BaseNotifier{name: "discord"},
+			enabled:      false,
+		}, nil
+	}
+
+	// Описание embed Discord показывает как Markdown, экранирование то же, что у Mattermost
+	renderer, err := NewRenderer("discord", cfg.Template, defaultDiscordTemplate, FormatMarkdown)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Discord нотификатор инициализирован")
+	return &DiscordNotifier{
+		BaseNotifier: BaseNotifier{name: "discord"},
+		client:       newHTTPClient(),
+		webhookURL:   cfg.WebhookURL,
+		username:     cfg.Username,
+		targets:      cfg.Targets,
+		renderer:     renderer,
+		enabled:      true,
+	}, nil
+}
+
+// Send отправляет уведомление в Discord
+func (d *DiscordNotifier) Send(alert *models.Alert) error {
+	return d.send(d.webhookURL, alert)
+}
+
+// SendTo отправляет уведомление в webhook именованного получателя из targets
+func (d *DiscordNotifier) SendTo(target string, alert *models.Alert) error {
+	dest, ok := d.targets[target]
+	if !ok {
+		return fmt.Errorf("неизвестный получатель discord: %s", target)
+	}
+	return d.send(firstNonEmpty(dest.WebhookURL, d.webhookURL), alert)
+}
+
+func (d *DiscordNotifier) send(webhookURL string, alert *models.Alert) error {
+	if !d.enabled {
+		return fmt.Errorf("discord нотификатор отключен")
+	}
+
+	msg, err := formatDiscordMessage(d.renderer, alert)
+	if err != nil {
+		return err
+	}
+	msg.Username = d.username
+
+	if err := sendJSON(d.client, http.MethodPost, webhookURL, nil, msg); err != nil {
+		return fmt.Errorf("ошибка отправки в Discord: %w", err)
+	}
+
+	log.Printf("Уведомление отправлено в Discord: %s", alert.Rule.Name)
+	return nil
+}
+
+// IsAvailable проверяет доступность нотификатора
+func (d *DiscordNotifier) IsAvailable() bool {
+	return d.enabled
+}
+
+// formatDiscordMessage форматирует сообщение в виде Discord embed
+func formatDiscordMessage(renderer *Renderer, alert *models.Alert) (discordMessage, error) {
+	description, err := renderer.Render(alert)
+	if err != nil {
+		return discordMessage{}, err
+	}
+
+	// Пользовательский шаблон может дать пустой текст: тогда в embed хватит заголовка
+	if strings.TrimSpace(description) == "" {
+		description = ""
+	}
+
+	embed := discordEmbed{
+		Title:       truncate(alertTitle(alert), discordTitleLimit),
+		URL:         firstLink(alert),
+		Color:       discordColor(levelColor(alert.Level)),
+		Description: truncate(description, discordDescriptionLimit),
+	}
+
+	if !alert.Email.Date.IsZero() {
+		embed.Timestamp = alert.Email.Date.Format(time.RFC3339)
+	}
+
+	return discordMessage{Embeds: []discordEmbed{embed}}, nil
+}
+
+// discordColor переводит цвет #rrggbb в число, которое ожидает Discord
+func discordColor(hex string) int {
+	color, err := strconv.ParseInt(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil {
+		return 0
+	}
+	return int(color)
+}
@@ -0,0 +1,101 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// levelEmoji возвращает эмодзи для уровня важности
+func levelEmoji(level models.AlertLevel) string {
+	switch level {
+	case models.AlertCritical:
+		return "🔴"
+	case models.AlertHigh:
+		return "🟠"
+	case models.AlertMedium:
+		return "🟡"
+	case models.AlertLow:
+		return "🔵"
+	default:
+		return "🔔"
+	}
+}
+
+// levelColor возвращает цвет уровня важности в формате #rrggbb
+func levelColor(level models.AlertLevel) string {
+	switch level {
+	case models.AlertCritical:
+		return "#d32f2f"
+	case models.AlertHigh:
+		return "#f57c00"
+	case models.AlertMedium:
+		return "#fbc02d"
+	case models.AlertLow:
+		return "#1976d2"
+	default:
+		return "#9e9e9e"
+	}
+}
+
+// firstLink возвращает первую ссылку из письма или пустую строку
+func firstLink(alert *models.Alert) string {
+	if alert.Email == nil || len(alert.Email.Links) == 0 {
+		return ""
+	}
+	return alert.Email.Links[0]
+}
+
+// alertTitle возвращает заголовок уведомления: эмодзи уровня и имя правила
+func alertTitle(alert *models.Alert) string {
+	return fmt.Sprintf("%s %s", levelEmoji(alert.Level), alert.Rule.Name)
+}
+
+// plainRenderer - встроенный простой шаблон для текстовых копий сообщений и Alert.Message
+var plainRenderer = sync.OnceValue(func() *Renderer {
+	renderer, err := NewRenderer("plain", "", defaultPlainTemplate, FormatPlain)
+	if err != nil {
+		panic(err) // встроенные шаблоны проверяются тестами
+	}
+	return renderer
+})
+
+// plainBody форматирует тело уведомления простым текстом по встроенному шаблону
+func plainBody(alert *models.Alert) string {
+	text, err := plainRenderer().Render(alert)
+	if err != nil {
+		log.Printf("Ошибка шаблона сообщения: %v", err)
+		return ""
+	}
+	return text
+}
+
+// Message возвращает текст алерта для Alert.Message: заголовок уведомления и тот же
+// встроенный шаблон, которым пользуются нотификаторы без своего шаблона
+func Message(alert *models.Alert) string {
+	return alertTitle(alert) + "\n" + plainBody(alert)
+}
+
+// firstNonEmpty возвращает первую непустую строку
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// truncate обрезает строку до max символов (рун)
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if max <= 0 {
+		return ""
+	}
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,121 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+type GotifyNotifier struct {
+	BaseNotifier
+	client    *http.Client
+	serverURL string
+	token     string
+	targets   map[string]string
+	renderer  *Renderer
+	enabled   bool
+}
+
+// gotifyMessage - тело запроса POST /message
+type gotifyMessage struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras,omitempty"`
+}
+
+func NewGotify(cfg *config.GotifyConfig) (*GotifyNotifier, error) {
+	if cfg == nil || !cfg.Enabled || cfg.ServerURL == "" || cfg.Token == "" {
+		return &GotifyNotifier{
+			BaseNotifier: BaseNotifier{name: "gotify"},
+			enabled:      false,
+		}, nil
+	}
+
+	renderer, err := NewRenderer("gotify", cfg.Template, defaultPlainTemplate, FormatPlain)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Gotify нотификатор инициализирован")
+	return &GotifyNotifier{
+		BaseNotifier: BaseNotifier{name: "gotify"},
+		client:       newHTTPClient(),
+		serverURL:    strings.TrimRight(cfg.ServerURL, "/"),
+		token:        cfg.Token,
+		targets:      cfg.Targets,
+		renderer:     renderer,
+		enabled:      true,
+	}, nil
+}
+
+// Send публикует уведомление в Gotify
+func (g *GotifyNotifier) Send(alert *models.Alert) error {
+	return g.send(g.token, alert)
+}
+
+// SendTo публикует уведомление от имени приложения из targets
+func (g *GotifyNotifier) SendTo(target string, alert *models.Alert) error {
+	token, ok := g.targets[target]
+	if !ok {
+		return fmt.Errorf("неизвестный получатель gotify: %s", target)
+	}
+	return g.send(token, alert)
+}
+
+func (g *GotifyNotifier) send(token string, alert *models.Alert) error {
+	if !g.enabled {
+		return fmt.Errorf("gotify нотификатор отключен")
+	}
+
+	text, err := g.renderer.Render(alert)
+	if err != nil {
+		return err
+	}
+
+	msg := gotifyMessage{
+		Title:    alertTitle(alert),
+		Message:  text,
+		Priority: gotifyPriority(alert.Level),
+	}
+
+	// Первая ссылка открывается по нажатию на уведомление
+	if link := firstLink(alert); link != "" {
+		msg.Extras = map[string]any{
+			"client::notification": map[string]any{
+				"click": map[string]string{"url": link},
+			},
+		}
+	}
+
+	headers := map[string]string{"X-Gotify-Key": token}
+	if err := sendJSON(g.client, http.MethodPost, g.serverURL+"/message", headers, msg); err != nil {
+		return fmt.Errorf("ошибка отправки в Gotify: %w", err)
+	}
+
+	log.Printf("Уведомление отправлено в Gotify: %s", alert.Rule.Name)
+	return nil
+}
+
+// IsAvailable проверяет доступность нотификатора
+func (g *GotifyNotifier) IsAvailable() bool {
+	return g.enabled
+}
+
+// gotifyPriority переводит уровень важности в приоритет Gotify (0..10)
+func gotifyPriority(level models.AlertLevel) int {
+	switch level {
+	case models.AlertCritical:
+		return 10
+	case models.AlertHigh:
+		return 7
+	case models.AlertMedium:
+		return 5
+	default:
+		return 2
+	}
+}
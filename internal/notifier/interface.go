@@ -9,7 +9,28 @@ type Notifier interface {
 	IsAvailable() bool
 }
 
-//BaseNotifier базовая структура для всех нотификаторов
+// TargetNotifier - нотификатор с именованными получателями (блок targets в конфиге)
+type TargetNotifier interface {
+	Notifier
+	SendTo(target string, alert *models.Alert) error
+}
+
+// UpdateNotifier - нотификатор, который умеет обновить уже отправленное сообщение.
+// Остальным нотификаторам повторы алертов (Alert.ReplacesID) отправляются как новые
+type UpdateNotifier interface {
+	Notifier
+	Update(target string, alert *models.Alert) error
+	SetMessageStore(store MessageStore)
+}
+
+// MessageStore хранит идентификаторы отправленных сообщений, чтобы повторы
+// обновляли их и после перезапуска. key различает нотификатор и чат
+type MessageStore interface {
+	SaveMessage(alertID models.ID, key string, messageID int) error
+	Message(alertID models.ID, key string) (int, bool)
+}
+
+// BaseNotifier базовая структура для всех нотификаторов
 type BaseNotifier struct {
 	name string
 }
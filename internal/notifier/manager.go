@@ -1,25 +1,77 @@
 package notifier
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/metrics"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
 )
 
 type Manager struct {
 	notifiers map[models.ActionType]Notifier
+	cfg       config.NotifiersConfig // с какими настройками созданы нотификаторы, см. Reload
+
+	mu       sync.Mutex
+	limiters map[models.ActionType]*tokenBucket
+	quiet    *quietHours // nil, если тихие часы выключены
+	now      func() time.Time
 }
 
 // NewManager создает и настраивает все нотификаторы из конфига
 func NewManager(cfg *config.Config) (*Manager, error) {
+	return newManager(cfg, nil)
+}
+
+// Reload создаёт менеджер для нового конфига. Нотификаторы, настройки которых не изменились,
+// переходят из m как есть: Telegram не присылает снова сообщение о запуске, desktop
+// не переподключается к D-Bus. Сам m не меняется, заменённые нотификаторы закрывает CloseUnused
+func (m *Manager) Reload(cfg *config.Config) (*Manager, error) {
+	return newManager(cfg, m)
+}
+
+func newManager(cfg *config.Config, prev *Manager) (_ *Manager, err error) {
 	manager := &Manager{
 		notifiers: make(map[models.ActionType]Notifier),
+		cfg:       cfg.Notifiers,
+		limiters:  make(map[models.ActionType]*tokenBucket),
+		now:       time.Now,
+	}
+	// Если дальше что-то не создалось, новые нотификаторы больше никому не нужны
+	defer func() {
+		if err != nil {
+			manager.CloseUnused(prev)
+		}
+	}()
+
+	var prevCfg config.NotifiersConfig
+	if prev != nil {
+		prevCfg = prev.cfg
+	}
+
+	if quiet := cfg.Notifiers.QuietHours; quiet != nil && quiet.Enabled {
+		schedule, err := newQuietHours(quiet)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка настройки тихих часов: %w", err)
+		}
+		manager.quiet = schedule
+	}
+
+	for actionType, limit := range cfg.Notifiers.RateLimits {
+		manager.limiters[actionType] = newTokenBucket(limit.PerMinute, limit.Burst, manager.now())
 	}
 
 	// Инициализируем Telegram нотификатор
-	if cfg.Notifiers.Telegram != nil && cfg.Notifiers.Telegram.Enabled {
+	if cfg.Notifiers.Telegram != nil && cfg.Notifiers.Telegram.Enabled &&
+		!manager.reuse(prev, models.ActionNotifyTelegram, prevCfg.Telegram, cfg.Notifiers.Telegram) {
 		telegram, err := NewTelegram(cfg.Notifiers.Telegram)
 		if err != nil {
 			return nil, fmt.Errorf("ошибка инициализации Telegram:%w", err)
@@ -27,10 +79,158 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 		manager.Register(models.ActionNotifyTelegram, telegram)
 	}
 
+	if cfg.Notifiers.Slack != nil && cfg.Notifiers.Slack.Enabled &&
+		!manager.reuse(prev, models.ActionNotifySlack, prevCfg.Slack, cfg.Notifiers.Slack) {
+		slack, err := NewSlack(cfg.Notifiers.Slack)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка инициализации Slack:%w", err)
+		}
+		manager.Register(models.ActionNotifySlack, slack)
+	}
+
+	if cfg.Notifiers.Mattermost != nil && cfg.Notifiers.Mattermost.Enabled &&
+		!manager.reuse(prev, models.ActionNotifyMattermost, prevCfg.Mattermost, cfg.Notifiers.Mattermost) {
+		mattermost, err := NewMattermost(cfg.Notifiers.Mattermost)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка инициализации Mattermost:%w", err)
+		}
+		manager.Register(models.ActionNotifyMattermost, mattermost)
+	}
+
+	if cfg.Notifiers.Discord != nil && cfg.Notifiers.Discord.Enabled &&
+		!manager.reuse(prev, models.ActionNotifyDiscord, prevCfg.Discord, cfg.Notifiers.Discord) {
+		discord, err := NewDiscord(cfg.Notifiers.Discord)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка инициализации Discord:%w", err)
+		}
+		manager.Register(models.ActionNotifyDiscord, discord)
+	}
+
+	if cfg.Notifiers.Matrix != nil && cfg.Notifiers.Matrix.Enabled &&
+		!manager.reuse(prev, models.ActionNotifyMatrix, prevCfg.Matrix, cfg.Notifiers.Matrix) {
+		matrix, err := NewMatrix(cfg.Notifiers.Matrix)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка инициализации Matrix:%w", err)
+		}
+		manager.Register(models.ActionNotifyMatrix, matrix)
+	}
+
+	if cfg.Notifiers.Ntfy != nil && cfg.Notifiers.Ntfy.Enabled &&
+		!manager.reuse(prev, models.ActionNotifyNtfy, prevCfg.Ntfy, cfg.Notifiers.Ntfy) {
+		ntfy, err := NewNtfy(cfg.Notifiers.Ntfy)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка инициализации ntfy:%w", err)
+		}
+		manager.Register(models.ActionNotifyNtfy, ntfy)
+	}
+
+	if cfg.Notifiers.Gotify != nil && cfg.Notifiers.Gotify.Enabled &&
+		!manager.reuse(prev, models.ActionNotifyGotify, prevCfg.Gotify, cfg.Notifiers.Gotify) {
+		gotify, err := NewGotify(cfg.Notifiers.Gotify)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка инициализации Gotify:%w", err)
+		}
+		manager.Register(models.ActionNotifyGotify, gotify)
+	}
+
+	if cfg.Notifiers.Desktop != nil && cfg.Notifiers.Desktop.Enabled &&
+		!manager.reuse(prev, models.ActionNotifyDesktop, prevCfg.Desktop, cfg.Notifiers.Desktop) {
+		desktop, err := NewDesktop(cfg.Notifiers.Desktop)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка инициализации desktop:%w", err)
+		}
+		manager.Register(models.ActionNotifyDesktop, desktop)
+	}
+
+	// Шаблоны из правил проверяем сразу, а не при первой отправке
+	if err := ValidateTemplates(cfg.Rules); err != nil {
+		return nil, err
+	}
+
 	log.Printf("Менеджер нотификаторов инициализирован. Доступно: %d", len(manager.notifiers))
 	return manager, nil
 }
 
+// ValidateTemplates проверяет шаблоны сообщений из правил
+func ValidateTemplates(rules []*models.Rule) error {
+	for _, rule := range rules {
+		for name, text := range rule.Templates {
+			if _, err := NewTemplate(fmt.Sprintf("%s/%s", name, rule.Name), text, FormatPlain); err != nil {
+				return fmt.Errorf("ошибка в шаблоне правила %s: %w", rule.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reuse переносит в m нотификатор actionType из prev, если его настройки не изменились
+func (m *Manager) reuse(prev *Manager, actionType models.ActionType, prevCfg, cfg any) bool {
+	if prev == nil || !reflect.DeepEqual(prevCfg, cfg) {
+		return false
+	}
+	notifier, ok := prev.notifiers[actionType]
+	if !ok {
+		return false
+	}
+	m.notifiers[actionType] = notifier
+	log.Printf("Нотификатор %s не изменился", notifier.Name())
+	return true
+}
+
+// TemplateIssues проверяет шаблоны и форматы включённых нотификаторов и шаблоны правил,
+// как при запуске, и возвращает все ошибки с местом в конфиге (для config validate)
+func TemplateIssues(cfg *config.Config) []config.Issue {
+	var issues []config.Issue
+	check := func(path, name, text string) {
+		if text == "" {
+			return
+		}
+		if _, err := NewTemplate(name, text, FormatPlain); err != nil {
+			issues = append(issues, cfg.NewIssue(path, err.Error()))
+		}
+	}
+
+	n := cfg.Notifiers
+	if t := n.Telegram; t != nil && t.Enabled {
+		format, err := ParseFormat(t.Format, FormatHTML)
+		switch {
+		case err != nil:
+			issues = append(issues, cfg.NewIssue("notifiers.telegram.format", err.Error()))
+		case format == FormatMrkdwn:
+			issues = append(issues, cfg.NewIssue("notifiers.telegram.format", fmt.Sprintf("telegram не поддерживает формат %s", format)))
+		}
+		check("notifiers.telegram.template", "telegram", t.Template)
+	}
+	if s := n.Slack; s != nil && s.Enabled {
+		check("notifiers.slack.template", "slack", s.Template)
+	}
+	if mm := n.Mattermost; mm != nil && mm.Enabled {
+		check("notifiers.mattermost.template", "mattermost", mm.Template)
+	}
+	if d := n.Discord; d != nil && d.Enabled {
+		check("notifiers.discord.template", "discord", d.Template)
+	}
+	if mx := n.Matrix; mx != nil && mx.Enabled {
+		check("notifiers.matrix.template", "matrix", mx.Template)
+	}
+	if nt := n.Ntfy; nt != nil && nt.Enabled {
+		check("notifiers.ntfy.template", "ntfy", nt.Template)
+	}
+	if g := n.Gotify; g != nil && g.Enabled {
+		check("notifiers.gotify.template", "gotify", g.Template)
+	}
+	if d := n.Desktop; d != nil && d.Enabled {
+		check("notifiers.desktop.template", "desktop", d.Template)
+	}
+
+	for i, rule := range cfg.Rules {
+		for _, name := range slices.Sorted(maps.Keys(rule.Templates)) {
+			check(fmt.Sprintf("rules[%d].templates.%s", i, name), fmt.Sprintf("%s/%s", name, rule.Name), rule.Templates[name])
+		}
+	}
+	return issues
+}
+
 func (m *Manager) Register(actionType models.ActionType, notifier Notifier) {
 	if notifier.IsAvailable() {
 		m.notifiers[actionType] = notifier
@@ -38,14 +238,106 @@ func (m *Manager) Register(actionType models.ActionType, notifier Notifier) {
 	}
 }
 
-// Send отправляет уведомление через соответствующий нотификатор
-func (m *Manager) Send(actionType models.ActionType, alert *models.Alert) error {
-	notifier, exists := m.notifiers[actionType]
+// Send отправляет уведомление через соответствующий нотификатор.
+// В тихие часы алерты ниже порога и некритичные алерты сверх лимита нотификатора
+// не отправляются: Send возвращает outbox.Deferral, и запись ждёт в очереди
+// до конца тихих часов или свободного токена, а потом уходит сводкой
+func (m *Manager) Send(action models.Action, alert *models.Alert) error {
+	if _, exists := m.notifiers[action.Type]; !exists {
+		return fmt.Errorf("нотификатор для действия %s не указан", action.Type)
+	}
+
+	// Повтор только обновляет прежнее сообщение, ни тихие часы, ни сводки к нему не относятся
+	if alert.IsRepeat() && m.canUpdate(action.Type) {
+		return m.Deliver(action, alert)
+	}
+
+	now := m.now()
+	if m.quiet != nil && m.quiet.holds(alert, now) {
+		return m.holdAlert(alert, now)
+	}
+
+	if alert.Level != models.AlertCritical && !m.allow(action.Type, now) {
+		return m.deferAlert(action, alert, now)
+	}
+
+	return m.Deliver(action, alert)
+}
+
+// Deliver отправляет уведомление без учёта тихих часов и лимитов
+func (m *Manager) Deliver(action models.Action, alert *models.Alert) error {
+	err := m.dispatch(action, alert)
+
+	notifierName := string(action.Type)
+	if err != nil {
+		metrics.Notifications.Inc(notifierName, "failed")
+		return err
+	}
+	metrics.Notifications.Inc(notifierName, "sent")
+	if alert.Email != nil && !alert.Email.Date.IsZero() {
+		metrics.DeliveryLatency.ObserveSince(alert.Email.Date, notifierName)
+	}
+	return nil
+}
+
+// dispatch выбирает способ отправки: обновление повтора, получатель или чат по умолчанию
+func (m *Manager) dispatch(action models.Action, alert *models.Alert) error {
+	notifier, exists := m.notifiers[action.Type]
 	if !exists {
-		return fmt.Errorf("нотификатор для действия %s не указан", actionType)
+		return fmt.Errorf("нотификатор для действия %s не указан", action.Type)
+	}
+
+	// Кто не умеет обновлять сообщения, получает повтор как новое сообщение
+	if updater, ok := notifier.(UpdateNotifier); ok && alert.IsRepeat() {
+		return updater.Update(action.Target, alert)
+	}
+
+	if action.Target == "" {
+		return notifier.Send(alert)
+	}
+
+	targeted, ok := notifier.(TargetNotifier)
+	if !ok {
+		return fmt.Errorf("нотификатор %s не поддерживает получателей", notifier.Name())
 	}
+	return targeted.SendTo(action.Target, alert)
+}
+
+// canUpdate проверяет, умеет ли нотификатор действия обновлять отправленные сообщения
+func (m *Manager) canUpdate(actionType models.ActionType) bool {
+	_, ok := m.notifiers[actionType].(UpdateNotifier)
+	return ok
+}
+
+// SetMessageStore передаёт хранилище отправленных сообщений нотификаторам,
+// которые обновляют повторы
+func (m *Manager) SetMessageStore(store MessageStore) {
+	for _, notifier := range m.notifiers {
+		if updater, ok := notifier.(UpdateNotifier); ok {
+			updater.SetMessageStore(store)
+		}
+	}
+}
 
-	return notifier.Send(alert)
+// Close освобождает ресурсы нотификаторов, которые их держат (соединение с D-Bus и т.п.)
+func (m *Manager) Close() error {
+	return m.CloseUnused(nil)
+}
+
+// CloseUnused закрывает нотификаторы, которые не перешли в next при Reload
+func (m *Manager) CloseUnused(next *Manager) error {
+	var errs []error
+	for actionType, notifier := range m.notifiers {
+		if next != nil && next.notifiers[actionType] == notifier {
+			continue
+		}
+		if closer, ok := notifier.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", actionType, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
 }
 
 func (m *Manager) GetAvailableNotifiers() []string {
@@ -0,0 +1,63 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func TestManagerReload(t *testing.T) {
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{
+		Ntfy:   &config.NtfyConfig{Enabled: true, ServerURL: "https://ntfy.example.com", Topic: "mail"},
+		Gotify: &config.GotifyConfig{Enabled: true, ServerURL: "https://gotify.example.com", Token: "old"},
+	}}
+	current, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Desktop без изменений переходит в новый менеджер без переподключения к D-Bus
+	desktop := testDesktop(t, &fakeBus{})
+	current.notifiers[models.ActionNotifyDesktop] = desktop
+	current.cfg.Desktop = &config.DesktopConfig{Enabled: true}
+
+	next := &config.Config{Notifiers: config.NotifiersConfig{
+		Ntfy:    &config.NtfyConfig{Enabled: true, ServerURL: "https://ntfy.example.com", Topic: "mail"},
+		Gotify:  &config.GotifyConfig{Enabled: true, ServerURL: "https://gotify.example.com", Token: "new"},
+		Desktop: &config.DesktopConfig{Enabled: true},
+	}}
+	reloaded, err := current.Reload(next)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if reloaded.notifiers[models.ActionNotifyNtfy] != current.notifiers[models.ActionNotifyNtfy] {
+		t.Error("unchanged ntfy must be reused")
+	}
+	if reloaded.notifiers[models.ActionNotifyDesktop] != desktop {
+		t.Error("unchanged desktop must be reused")
+	}
+	if reloaded.notifiers[models.ActionNotifyGotify] == current.notifiers[models.ActionNotifyGotify] {
+		t.Error("changed gotify must be recreated")
+	}
+
+	if err := current.CloseUnused(reloaded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !desktop.IsAvailable() {
+		t.Error("reused desktop must not be closed")
+	}
+
+	// Без desktop в новом конфиге прежний закрывается
+	without, err := reloaded.Reload(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := reloaded.CloseUnused(without); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if desktop.IsAvailable() {
+		t.Error("replaced desktop must be closed")
+	}
+}
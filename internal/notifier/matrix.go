@@ -0,0 +1,111 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+type MatrixNotifier struct {
+	BaseNotifier
+	client        *http.Client
+	homeserverURL string
+	accessToken   string
+	roomID        string
+	targets       map[string]string
+	renderer      *Renderer
+	enabled       bool
+}
+
+// matrixMessage - событие m.room.message с HTML форматированием
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+func NewMatrix(cfg *config.MatrixConfig) (*MatrixNotifier, error) {
+	if cfg == nil || !cfg.Enabled || cfg.HomeserverURL == "" || cfg.AccessToken == "" || cfg.RoomID == "" {
+		return &MatrixNotifier{
+			BaseNotifier: BaseNotifier{name: "matrix"},
+			enabled:      false,
+		}, nil
+	}
+
+	renderer, err := NewRenderer("matrix", cfg.Template, defaultMatrixTemplate, FormatHTML)
+	if err != nil {
+		return nil, err
+	}
+	// В Matrix переводы строк в HTML не отображаются, нужен свой шаблон сводки
+	if renderer.summary, err = NewTemplate("matrix/summary", summaryMatrixTemplate, FormatHTML); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Matrix нотификатор инициализирован для комнаты: %s", cfg.RoomID)
+	return &MatrixNotifier{
+		BaseNotifier:  BaseNotifier{name: "matrix"},
+		client:        newHTTPClient(),
+		homeserverURL: strings.TrimRight(cfg.HomeserverURL, "/"),
+		accessToken:   cfg.AccessToken,
+		roomID:        cfg.RoomID,
+		targets:       cfg.Targets,
+		renderer:      renderer,
+		enabled:       true,
+	}, nil
+}
+
+// Send отправляет уведомление в комнату Matrix
+func (m *MatrixNotifier) Send(alert *models.Alert) error {
+	return m.send(m.roomID, alert)
+}
+
+// SendTo отправляет уведомление в именованную комнату из targets
+func (m *MatrixNotifier) SendTo(target string, alert *models.Alert) error {
+	roomID, ok := m.targets[target]
+	if !ok {
+		return fmt.Errorf("неизвестный получатель matrix: %s", target)
+	}
+	return m.send(roomID, alert)
+}
+
+func (m *MatrixNotifier) send(roomID string, alert *models.Alert) error {
+	if !m.enabled {
+		return fmt.Errorf("matrix нотификатор отключен")
+	}
+
+	// txnId делает запрос идемпотентным: повтор с тем же ID не создаст дубль
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		m.homeserverURL, url.PathEscape(roomID), url.PathEscape(string(alert.ID)))
+	headers := map[string]string{"Authorization": "Bearer " + m.accessToken}
+
+	html, err := m.renderer.Render(alert)
+	if err != nil {
+		return err
+	}
+
+	// body - запасной вариант для клиентов без поддержки HTML
+	msg := matrixMessage{
+		MsgType:       "m.text",
+		Body:          alertTitle(alert) + "\n" + plainBody(alert),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: html,
+	}
+
+	if err := sendJSON(m.client, http.MethodPut, endpoint, headers, msg); err != nil {
+		return fmt.Errorf("ошибка отправки в Matrix: %w", err)
+	}
+
+	log.Printf("Уведомление отправлено в Matrix: %s", alert.Rule.Name)
+	return nil
+}
+
+// IsAvailable проверяет доступность нотификатора
+func (m *MatrixNotifier) IsAvailable() bool {
+	return m.enabled
+}
@@ -0,0 +1,114 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// MattermostNotifier отправляет уведомления в Mattermost через incoming webhook.
+// Mattermost принимает Slack-совместимые attachments, но текст в них - обычный Markdown,
+// а не Slack mrkdwn: HTML-сущности он показывает как есть, поэтому экранирование своё.
+type MattermostNotifier struct {
+	BaseNotifier
+	client     *http.Client
+	webhookURL string
+	channel    string
+	username   string
+	targets    map[string]config.WebhookTarget
+	renderer   *Renderer
+	enabled    bool
+}
+
+func NewMattermost(cfg *config.MattermostConfig) (*MattermostNotifier, error) {
+	if cfg == nil || !cfg.Enabled || cfg.WebhookURL == "" {
+		return &MattermostNotifier{
+			BaseNotifier: BaseNotifier{name: "mattermost"},
+			enabled:      false,
+		}, nil
+	}
+
+	renderer, err := NewRenderer("mattermost", cfg.Template, defaultMattermostTemplate, FormatMarkdown)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Mattermost нотификатор инициализирован")
+	return &MattermostNotifier{
+		BaseNotifier: BaseNotifier{name: "mattermost"},
+		client:       newHTTPClient(),
+		webhookURL:   cfg.WebhookURL,
+		channel:      cfg.Channel,
+		username:     cfg.Username,
+		targets:      cfg.Targets,
+		renderer:     renderer,
+		enabled:      true,
+	}, nil
+}
+
+// Send отправляет уведомление в Mattermost
+func (m *MattermostNotifier) Send(alert *models.Alert) error {
+	return m.send(config.WebhookTarget{}, alert)
+}
+
+// SendTo отправляет уведомление именованному получателю из targets
+func (m *MattermostNotifier) SendTo(target string, alert *models.Alert) error {
+	dest, ok := m.targets[target]
+	if !ok {
+		return fmt.Errorf("неизвестный получатель mattermost: %s", target)
+	}
+	return m.send(dest, alert)
+}
+
+// send отправляет сообщение, незаполненные поля dest берутся из основных настроек
+func (m *MattermostNotifier) send(dest config.WebhookTarget, alert *models.Alert) error {
+	if !m.enabled {
+		return fmt.Errorf("mattermost нотификатор отключен")
+	}
+
+	msg, err := formatMattermostMessage(m.renderer, alert)
+	if err != nil {
+		return err
+	}
+	msg.Channel = firstNonEmpty(dest.Channel, m.channel)
+	msg.Username = m.username
+
+	webhookURL := firstNonEmpty(dest.WebhookURL, m.webhookURL)
+	if err := sendJSON(m.client, http.MethodPost, webhookURL, nil, msg); err != nil {
+		return fmt.Errorf("ошибка отправки в Mattermost: %w", err)
+	}
+
+	log.Printf("Уведомление отправлено в Mattermost: %s", alert.Rule.Name)
+	return nil
+}
+
+// IsAvailable проверяет доступность нотификатора
+func (m *MattermostNotifier) IsAvailable() bool {
+	return m.enabled
+}
+
+// formatMattermostMessage форматирует сообщение в виде attachment. Заголовок и fallback
+// Mattermost показывает простым текстом, Markdown понимают только text вложения и сообщения
+func formatMattermostMessage(renderer *Renderer, alert *models.Alert) (slackMessage, error) {
+	text, err := renderer.Render(alert)
+	if err != nil {
+		return slackMessage{}, err
+	}
+
+	title := alertTitle(alert)
+	attachment := slackAttachment{
+		Fallback:  fmt.Sprintf("%s: %s", title, alert.Email.Subject),
+		Color:     levelColor(alert.Level),
+		Title:     title,
+		TitleLink: firstLink(alert),
+		Text:      text,
+	}
+
+	return slackMessage{
+		Text:        escapeCommonMark(title),
+		Attachments: []slackAttachment{attachment},
+	}, nil
+}
@@ -0,0 +1,135 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// NtfyNotifier публикует уведомления в топик ntfy.
+// ntfy также является дистрибьютором UnifiedPush, поэтому подходит для него.
+type NtfyNotifier struct {
+	BaseNotifier
+	client    *http.Client
+	serverURL string
+	topic     string
+	token     string
+	targets   map[string]string
+	renderer  *Renderer
+	enabled   bool
+}
+
+// ntfyMessage - тело запроса публикации в формате JSON
+type ntfyMessage struct {
+	Topic    string       `json:"topic"`
+	Title    string       `json:"title"`
+	Message  string       `json:"message"`
+	Priority int          `json:"priority"`
+	Click    string       `json:"click,omitempty"`
+	Actions  []ntfyAction `json:"actions,omitempty"`
+}
+
+type ntfyAction struct {
+	Action string `json:"action"`
+	Label  string `json:"label"`
+	URL    string `json:"url"`
+}
+
+func NewNtfy(cfg *config.NtfyConfig) (*NtfyNotifier, error) {
+	if cfg == nil || !cfg.Enabled || cfg.ServerURL == "" || cfg.Topic == "" {
+		return &NtfyNotifier{
+			BaseNotifier: BaseNotifier{name: "ntfy"},
+			enabled:      false,
+		}, nil
+	}
+
+	renderer, err := NewRenderer("ntfy", cfg.Template, defaultPlainTemplate, FormatPlain)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("ntfy нотификатор инициализирован для топика: %s", cfg.Topic)
+	return &NtfyNotifier{
+		BaseNotifier: BaseNotifier{name: "ntfy"},
+		client:       newHTTPClient(),
+		serverURL:    strings.TrimRight(cfg.ServerURL, "/"),
+		topic:        cfg.Topic,
+		token:        cfg.Token,
+		targets:      cfg.Targets,
+		renderer:     renderer,
+		enabled:      true,
+	}, nil
+}
+
+// Send публикует уведомление в ntfy
+func (n *NtfyNotifier) Send(alert *models.Alert) error {
+	return n.send(n.topic, alert)
+}
+
+// SendTo публикует уведомление в именованный топик из targets
+func (n *NtfyNotifier) SendTo(target string, alert *models.Alert) error {
+	topic, ok := n.targets[target]
+	if !ok {
+		return fmt.Errorf("неизвестный получатель ntfy: %s", target)
+	}
+	return n.send(topic, alert)
+}
+
+func (n *NtfyNotifier) send(topic string, alert *models.Alert) error {
+	if !n.enabled {
+		return fmt.Errorf("ntfy нотификатор отключен")
+	}
+
+	text, err := n.renderer.Render(alert)
+	if err != nil {
+		return err
+	}
+
+	msg := ntfyMessage{
+		Topic:    topic,
+		Title:    alertTitle(alert),
+		Message:  text,
+		Priority: ntfyPriority(alert.Level),
+	}
+
+	// Первая ссылка открывается по нажатию на уведомление и кнопкой
+	if link := firstLink(alert); link != "" {
+		msg.Click = link
+		msg.Actions = []ntfyAction{{Action: "view", Label: "Открыть", URL: link}}
+	}
+
+	var headers map[string]string
+	if n.token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + n.token}
+	}
+
+	if err := sendJSON(n.client, http.MethodPost, n.serverURL, headers, msg); err != nil {
+		return fmt.Errorf("ошибка отправки в ntfy: %w", err)
+	}
+
+	log.Printf("Уведомление отправлено в ntfy: %s", alert.Rule.Name)
+	return nil
+}
+
+// IsAvailable проверяет доступность нотификатора
+func (n *NtfyNotifier) IsAvailable() bool {
+	return n.enabled
+}
+
+// ntfyPriority переводит уровень важности в приоритет ntfy (1..5)
+func ntfyPriority(level models.AlertLevel) int {
+	switch level {
+	case models.AlertCritical:
+		return 5
+	case models.AlertHigh:
+		return 4
+	case models.AlertMedium:
+		return 3
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,113 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/outbox"
+)
+
+// quietWindow - разобранный интервал тихих часов, время в минутах от полуночи
+type quietWindow struct {
+	days [7]bool
+	from int
+	to   int
+}
+
+// quietHours - расписание тихих часов
+type quietHours struct {
+	loc      *time.Location
+	minLevel models.AlertLevel
+	windows  []quietWindow
+}
+
+func newQuietHours(cfg *config.QuietHoursConfig) (*quietHours, error) {
+	loc, err := cfg.GetLocation()
+	if err != nil {
+		return nil, fmt.Errorf("неизвестный часовой пояс %s: %w", cfg.Timezone, err)
+	}
+
+	q := &quietHours{loc: loc, minLevel: cfg.GetMinLevel()}
+	for _, w := range cfg.Schedule {
+		var window quietWindow
+		if window.from, err = config.ParseClock(w.From); err != nil {
+			return nil, err
+		}
+		if window.to, err = config.ParseClock(w.To); err != nil {
+			return nil, err
+		}
+
+		if len(w.Days) == 0 {
+			window.days = [7]bool{true, true, true, true, true, true, true}
+		}
+		for _, name := range w.Days {
+			day, err := config.ParseWeekday(name)
+			if err != nil {
+				return nil, err
+			}
+			window.days[day] = true
+		}
+		q.windows = append(q.windows, window)
+	}
+	return q, nil
+}
+
+// active проверяет, попадает ли момент t в тихие часы
+func (q *quietHours) active(t time.Time) bool {
+	t = t.In(q.loc)
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, w := range q.windows {
+		if w.from < w.to {
+			if w.days[today] && minute >= w.from && minute < w.to {
+				return true
+			}
+			continue
+		}
+		// Интервал через полночь: вечер дня начала или утро следующего дня
+		if w.days[today] && minute >= w.from || w.days[yesterday] && minute < w.to {
+			return true
+		}
+	}
+	return false
+}
+
+// holds проверяет, нужно ли придержать алерт до конца тихих часов
+func (q *quietHours) holds(alert *models.Alert, now time.Time) bool {
+	minLevel := q.minLevel
+	if alert.Rule != nil && alert.Rule.QuietHours != nil {
+		if alert.Rule.QuietHours.Ignore {
+			return false
+		}
+		if alert.Rule.QuietHours.MinLevel != 0 {
+			minLevel = alert.Rule.QuietHours.MinLevel
+		}
+	}
+	return alert.Level < minLevel && q.active(now)
+}
+
+// end возвращает момент окончания тихих часов, в которые попадает now.
+// Интервалы могут идти подряд, поэтому ищем первую минуту вне их, но не дальше недели
+func (q *quietHours) end(now time.Time) time.Time {
+	t := now.Truncate(time.Minute)
+	for range 7 * 24 * 60 {
+		t = t.Add(time.Minute)
+		if !q.active(t) {
+			return t
+		}
+	}
+	return t
+}
+
+// holdAlert откладывает алерт до конца тихих часов: запись ждёт в очереди
+// и уходит в утренней сводке вместе с остальными придержанными
+func (m *Manager) holdAlert(alert *models.Alert, now time.Time) error {
+	until := m.quiet.end(now)
+	log.Printf("Тихие часы, алерт отложен до %s: %s", until.Format("15:04 02.01"), ruleName(alert))
+	return outbox.Defer(until, GroupQuiet)
+}
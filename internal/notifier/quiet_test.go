@@ -0,0 +1,107 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func testQuietHours(t *testing.T) *quietHours {
+	t.Helper()
+	quiet, err := newQuietHours(&config.QuietHoursConfig{
+		Enabled:  true,
+		Timezone: "UTC",
+		Schedule: []config.QuietWindow{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, From: "23:00", To: "07:00"},
+			{Days: []string{"sat", "sun"}, From: "13:00", To: "15:00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return quiet
+}
+
+func TestQuietHoursActive(t *testing.T) {
+	quiet := testQuietHours(t)
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected bool
+	}{
+		// 1 октября 2025 - среда
+		{"Вечер среды", time.Date(2025, 10, 1, 23, 30, 0, 0, time.UTC), true},
+		{"Утро четверга", time.Date(2025, 10, 2, 6, 59, 0, 0, time.UTC), true},
+		{"Конец интервала", time.Date(2025, 10, 2, 7, 0, 0, 0, time.UTC), false},
+		{"День среды", time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), false},
+		{"Утро субботы после пятницы", time.Date(2025, 10, 4, 3, 0, 0, 0, time.UTC), true},
+		{"Утро понедельника после воскресенья", time.Date(2025, 10, 6, 3, 0, 0, 0, time.UTC), false},
+		{"Дневной сон в воскресенье", time.Date(2025, 10, 5, 14, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quiet.active(tt.time); got != tt.expected {
+				t.Errorf("incorrect result, expected: '%v', got: '%v'", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	quiet := testQuietHours(t)
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected time.Time
+	}{
+		{"Вечер среды", time.Date(2025, 10, 1, 23, 30, 15, 0, time.UTC), time.Date(2025, 10, 2, 7, 0, 0, 0, time.UTC)},
+		{"Дневной сон в субботу", time.Date(2025, 10, 4, 14, 0, 0, 0, time.UTC), time.Date(2025, 10, 4, 15, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quiet.end(tt.time); !got.Equal(tt.expected) {
+				t.Errorf("incorrect result, expected: '%v', got: '%v'", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestQuietHoursDefers(t *testing.T) {
+	now := time.Date(2025, 10, 1, 23, 30, 0, 0, time.UTC)
+	m, fake := limitedManager(&now, 60, 10)
+	m.quiet = testQuietHours(t)
+	action := models.Action{Type: models.ActionNotifyTelegram}
+
+	// Правило с пониженным порогом
+	override := burstAlert(3, "Медосмотр", models.AlertMedium)
+	override.Rule.QuietHours = &models.RuleQuietHours{MinLevel: models.AlertMedium}
+
+	var held int
+	morning := time.Date(2025, 10, 2, 7, 0, 0, 0, time.UTC)
+	for _, alert := range []*models.Alert{
+		burstAlert(0, "Рассылка", models.AlertMedium),
+		burstAlert(1, "Рассылка", models.AlertLow),
+		burstAlert(2, "Дедлайн", models.AlertHigh), // выше порога уходит сразу
+		override,
+	} {
+		if d := deferral(t, m.Send(action, alert)); d != nil {
+			held++
+			if !d.Until.Equal(morning) || d.Group != GroupQuiet {
+				t.Errorf("incorrect deferral, got: %v %q", d.Until, d.Group)
+			}
+		}
+	}
+	if len(fake.sent) != 2 || held != 2 {
+		t.Fatalf("expected 2 sent and 2 held, got: %d sent, %d held", len(fake.sent), held)
+	}
+
+	// Утром сводка уходит
+	now = morning
+	if err := m.Send(action, burstAlert(4, "Рассылка", models.AlertLow)); err != nil {
+		t.Errorf("expected delivery after quiet hours, got: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package notifier
+
+import (
+	"math"
+	"time"
+)
+
+// tokenBucket - ограничитель частоты: ведро на burst токенов,
+// которое пополняется со скоростью rate токенов в секунду
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(perMinute float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   perMinute / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// allow забирает токен, если он есть
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.refill(now)
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// next возвращает момент, когда появится следующий токен
+func (b *tokenBucket) next(now time.Time) time.Time {
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		return now
+	}
+	return now.Add(time.Duration(math.Ceil((1 - b.tokens) / b.rate * float64(time.Second))))
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
@@ -0,0 +1,134 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/outbox"
+)
+
+// fakeNotifier запоминает отправленные алерты
+type fakeNotifier struct {
+	BaseNotifier
+	sent []*models.Alert
+}
+
+func (f *fakeNotifier) Send(alert *models.Alert) error {
+	f.sent = append(f.sent, alert)
+	return nil
+}
+
+func (f *fakeNotifier) IsAvailable() bool { return true }
+
+func limitedManager(now *time.Time, perMinute float64, burst int) (*Manager, *fakeNotifier) {
+	fake := &fakeNotifier{BaseNotifier: BaseNotifier{name: "telegram"}}
+	m := &Manager{
+		notifiers: map[models.ActionType]Notifier{models.ActionNotifyTelegram: fake},
+		limiters:  make(map[models.ActionType]*tokenBucket),
+		now:       func() time.Time { return *now },
+	}
+	m.limiters[models.ActionNotifyTelegram] = newTokenBucket(perMinute, burst, *now)
+	return m, fake
+}
+
+func burstAlert(i int, rule string, level models.AlertLevel) *models.Alert {
+	return &models.Alert{
+		ID:    models.ID(fmt.Sprintf("alert-%d", i)),
+		Rule:  &models.Rule{Name: rule},
+		Email: &models.Email{Subject: fmt.Sprintf("Письмо %d", i)},
+		Score: 60,
+		Level: level,
+	}
+}
+
+// deferral возвращает Deferral из ошибки Send или nil
+func deferral(t *testing.T, err error) *outbox.Deferral {
+	t.Helper()
+	var d *outbox.Deferral
+	if err != nil && !errors.As(err, &d) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return d
+}
+
+func TestRateLimitDefers(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	m, fake := limitedManager(&now, 6, 2)
+	action := models.Action{Type: models.ActionNotifyTelegram}
+
+	var deferred []*outbox.Deferral
+	for i := range 5 {
+		if d := deferral(t, m.Send(action, burstAlert(i, "Рассылка", models.AlertMedium))); d != nil {
+			deferred = append(deferred, d)
+		}
+	}
+	if len(fake.sent) != 2 || len(deferred) != 3 {
+		t.Fatalf("expected 2 sent and 3 deferred, got: %d sent, %d deferred", len(fake.sent), len(deferred))
+	}
+
+	// Отложенные ждут следующего токена (6 в минуту - раз в 10 секунд) в группе правила
+	for _, d := range deferred {
+		if !d.Until.Equal(now.Add(10*time.Second)) || d.Group != "limit:Рассылка" {
+			t.Errorf("incorrect deferral, got: %v %q", d.Until, d.Group)
+		}
+	}
+
+	// Критичный алерт лимит не учитывает
+	if err := m.Send(action, burstAlert(5, "Дедлайн", models.AlertCritical)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.sent) != 3 {
+		t.Errorf("critical alert must bypass limit, got: %d sent", len(fake.sent))
+	}
+
+	// Через 10 секунд появился токен: сводка отложенных уходит
+	now = now.Add(10 * time.Second)
+	summary := MergeDeferred(deferred[0].Group, []*models.Alert{
+		burstAlert(2, "Рассылка", models.AlertMedium),
+		burstAlert(3, "Рассылка", models.AlertMedium),
+		burstAlert(4, "Рассылка", models.AlertMedium),
+	})
+	if err := m.Send(action, summary); err != nil {
+		t.Fatalf("expected summary to be sent, got: %v", err)
+	}
+	if !summary.IsSummary() || len(summary.Group) != 3 {
+		t.Errorf("expected summary of 3 alerts, got: %+v", summary)
+	}
+	if summary.Email.Subject != "Ещё 3 алертов по правилу Рассылка" {
+		t.Errorf("incorrect summary title, got: '%v'", summary.Email.Subject)
+	}
+}
+
+func TestMergeDeferred(t *testing.T) {
+	single := burstAlert(0, "Рассылка", models.AlertLow)
+	if MergeDeferred(GroupQuiet, []*models.Alert{single}) != single {
+		t.Error("single alert must be sent as is")
+	}
+
+	morning := MergeDeferred(GroupQuiet, []*models.Alert{single, burstAlert(1, "Дедлайн", models.AlertMedium)})
+	if morning.Email.Subject != "Утренняя сводка: 2 алертов" || morning.Level != models.AlertMedium {
+		t.Errorf("incorrect morning digest, got: '%v', %v", morning.Email.Subject, morning.Level)
+	}
+}
+
+func TestRepeatWithoutUpdate(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	m, fake := limitedManager(&now, 6, 1)
+	action := models.Action{Type: models.ActionNotifyTelegram}
+
+	// Нотификатор не умеет обновлять сообщения: повтор уходит новым сообщением и под лимитом
+	for i := range 2 {
+		alert := burstAlert(i, "Медосмотр", models.AlertHigh)
+		alert.ReplacesID = "alert-0"
+		alert.Repeats = i + 1
+		if d := deferral(t, m.Send(action, alert)); (d != nil) != (i == 1) {
+			t.Errorf("repeat %d: unexpected deferral: %v", i, d)
+		}
+	}
+	if len(fake.sent) != 1 {
+		t.Errorf("expected repeat sent as new message, got: %d sent", len(fake.sent))
+	}
+}
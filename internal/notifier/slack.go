@@ -0,0 +1,126 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+type SlackNotifier struct {
+	BaseNotifier
+	client     *http.Client
+	webhookURL string
+	channel    string
+	username   string
+	targets    map[string]config.WebhookTarget
+	renderer   *Renderer
+	enabled    bool
+}
+
+// slackMessage - тело запроса incoming webhook (формат Slack, его же понимает Mattermost)
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Fallback  string `json:"fallback"`
+	Color     string `json:"color"`
+	Title     string `json:"title"`
+	TitleLink string `json:"title_link,omitempty"`
+	Text      string `json:"text"`
+}
+
+func NewSlack(cfg *config.SlackConfig) (*SlackNotifier, error) {
+	if cfg == nil || !cfg.Enabled || cfg.WebhookURL == "" {
+		return &SlackNotifier{
+			BaseNotifier: BaseNotifier{name: "slack"},
+			enabled:      false,
+		}, nil
+	}
+
+	renderer, err := NewRenderer("slack", cfg.Template, defaultMrkdwnTemplate, FormatMrkdwn)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Slack нотификатор инициализирован")
+	return &SlackNotifier{
+		BaseNotifier: BaseNotifier{name: "slack"},
+		client:       newHTTPClient(),
+		webhookURL:   cfg.WebhookURL,
+		channel:      cfg.Channel,
+		username:     cfg.Username,
+		targets:      cfg.Targets,
+		renderer:     renderer,
+		enabled:      true,
+	}, nil
+}
+
+// Send отправляет уведомление в Slack
+func (s *SlackNotifier) Send(alert *models.Alert) error {
+	return s.send(config.WebhookTarget{}, alert)
+}
+
+// SendTo отправляет уведомление именованному получателю из targets
+func (s *SlackNotifier) SendTo(target string, alert *models.Alert) error {
+	dest, ok := s.targets[target]
+	if !ok {
+		return fmt.Errorf("неизвестный получатель slack: %s", target)
+	}
+	return s.send(dest, alert)
+}
+
+// send отправляет сообщение, незаполненные поля dest берутся из основных настроек
+func (s *SlackNotifier) send(dest config.WebhookTarget, alert *models.Alert) error {
+	if !s.enabled {
+		return fmt.Errorf("slack нотификатор отключен")
+	}
+
+	msg, err := formatSlackMessage(s.renderer, alert)
+	if err != nil {
+		return err
+	}
+	msg.Channel = firstNonEmpty(dest.Channel, s.channel)
+	msg.Username = s.username
+
+	webhookURL := firstNonEmpty(dest.WebhookURL, s.webhookURL)
+	if err := sendJSON(s.client, http.MethodPost, webhookURL, nil, msg); err != nil {
+		return fmt.Errorf("ошибка отправки в Slack: %w", err)
+	}
+
+	log.Printf("Уведомление отправлено в Slack: %s", alert.Rule.Name)
+	return nil
+}
+
+// IsAvailable проверяет доступность нотификатора
+func (s *SlackNotifier) IsAvailable() bool {
+	return s.enabled
+}
+
+// formatSlackMessage форматирует сообщение в виде Slack attachment
+func formatSlackMessage(renderer *Renderer, alert *models.Alert) (slackMessage, error) {
+	text, err := renderer.Render(alert)
+	if err != nil {
+		return slackMessage{}, err
+	}
+
+	title := escapeHTML(alertTitle(alert))
+	attachment := slackAttachment{
+		Fallback:  fmt.Sprintf("%s: %s", title, escapeHTML(alert.Email.Subject)),
+		Color:     levelColor(alert.Level),
+		Title:     title,
+		TitleLink: firstLink(alert),
+		Text:      text,
+	}
+
+	return slackMessage{
+		Text:        title,
+		Attachments: []slackAttachment{attachment},
+	}, nil
+}
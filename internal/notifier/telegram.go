@@ -1,9 +1,13 @@
 package notifier
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
@@ -12,11 +16,18 @@ import (
 
 type TelegramNotifier struct {
 	BaseNotifier
-	bot     *tgbotapi.BotAPI
-	chatID  int64
-	enabled bool
+	bot      *tgbotapi.BotAPI
+	chatID   int64
+	targets  map[string]config.TelegramTarget
+	renderer *Renderer
+	enabled  bool
+
+	mu       sync.Mutex
+	messages MessageStore // отправленные сообщения для обновления повторов, nil - не запоминаем
 }
 
+var _ UpdateNotifier = (*TelegramNotifier)(nil)
+
 func NewTelegram(cfg *config.TelegramConfig) (*TelegramNotifier, error) {
 	if cfg == nil || !cfg.Enabled || cfg.BotToken == "" || cfg.ChatID == 0 {
 		return &TelegramNotifier{
@@ -25,6 +36,19 @@ func NewTelegram(cfg *config.TelegramConfig) (*TelegramNotifier, error) {
 		}, nil
 	}
 
+	format, err := ParseFormat(cfg.Format, FormatHTML)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatMrkdwn {
+		return nil, fmt.Errorf("telegram не поддерживает формат %s", format)
+	}
+
+	renderer, err := NewRenderer("telegram", cfg.Template, defaultTemplate(format), format)
+	if err != nil {
+		return nil, err
+	}
+
 	bot, err := tgbotapi.NewBotAPI(cfg.BotToken)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания Telegram бота: %w", err)
@@ -34,6 +58,8 @@ func NewTelegram(cfg *config.TelegramConfig) (*TelegramNotifier, error) {
 		BaseNotifier: BaseNotifier{name: "telegram"},
 		bot:          bot,
 		chatID:       cfg.ChatID,
+		targets:      cfg.Targets,
+		renderer:     renderer,
 		enabled:      true,
 	}
 
@@ -48,51 +74,148 @@ func NewTelegram(cfg *config.TelegramConfig) (*TelegramNotifier, error) {
 
 // Send отправляет уведомление в Telegram
 func (t *TelegramNotifier) Send(alert *models.Alert) error {
+	return t.send(t.chatID, 0, alert)
+}
+
+// SendTo отправляет уведомление именованному получателю из targets
+func (t *TelegramNotifier) SendTo(target string, alert *models.Alert) error {
+	dest, ok := t.targets[target]
+	if !ok {
+		return fmt.Errorf("неизвестный получатель telegram: %s", target)
+	}
+	return t.send(dest.ChatID, dest.ThreadID, alert)
+}
+
+// SetMessageStore задаёт хранилище отправленных сообщений
+func (t *TelegramNotifier) SetMessageStore(store MessageStore) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = store
+}
+
+// Update обновляет сообщение, отправленное для алерта alert.ReplacesID.
+// Если сообщение не найдено, отправляет новое
+func (t *TelegramNotifier) Update(target string, alert *models.Alert) error {
+	chatID, threadID := t.chatID, 0
+	if target != "" {
+		dest, ok := t.targets[target]
+		if !ok {
+			return fmt.Errorf("неизвестный получатель telegram: %s", target)
+		}
+		chatID, threadID = dest.ChatID, dest.ThreadID
+	}
+
+	messageID, ok := t.message(alert.ReplacesID, chatID)
+	if !ok {
+		return t.send(chatID, threadID, alert)
+	}
+
 	if !t.enabled {
 		return fmt.Errorf("telegram нотификатор отключен")
 	}
 
-	message := t.formatMessage(alert)
+	message, err := t.renderer.Render(alert)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(chatID, 10))
+	params.Set("message_id", strconv.Itoa(messageID))
+	params.Set("text", fmt.Sprintf("%s\n🔁 Повторов: %d", message, alert.Repeats))
+	if mode := telegramParseMode(t.renderer.Format()); mode != "" {
+		params.Set("parse_mode", mode)
+	}
 
-	msg := tgbotapi.NewMessage(t.chatID, message)
-	msg.ParseMode = "HTML"
+	if _, err := t.bot.MakeRequest("editMessageText", params); err != nil {
+		return fmt.Errorf("ошибка обновления сообщения в Telegram: %w", err)
+	}
 
-	_, err := t.bot.Send(msg)
+	log.Printf("Сообщение в Telegram обновлено: %s (повторов: %d)", alert.Rule.Name, alert.Repeats)
+	return nil
+}
+
+// send отправляет сообщение в чат и, если threadID не 0, в тему группы
+func (t *TelegramNotifier) send(chatID int64, threadID int, alert *models.Alert) error {
+	if !t.enabled {
+		return fmt.Errorf("telegram нотификатор отключен")
+	}
+
+	message, err := t.renderer.Render(alert)
+	if err != nil {
+		return err
+	}
+
+	// tgbotapi v4 не знает про message_thread_id, поэтому собираем запрос сами
+	params := url.Values{}
+	params.Set("chat_id", strconv.FormatInt(chatID, 10))
+	params.Set("text", message)
+	if mode := telegramParseMode(t.renderer.Format()); mode != "" {
+		params.Set("parse_mode", mode)
+	}
+	if threadID != 0 {
+		params.Set("message_thread_id", strconv.Itoa(threadID))
+	}
+
+	resp, err := t.bot.MakeRequest("sendMessage", params)
 	if err != nil {
 		return fmt.Errorf("ошибка отправки в Telegram: %w", err)
 	}
 
+	// Запоминаем сообщение, чтобы повторы алерта обновляли его
+	var sent tgbotapi.Message
+	if err := json.Unmarshal(resp.Result, &sent); err == nil {
+		id := alert.ID
+		if alert.IsRepeat() {
+			id = alert.ReplacesID
+		}
+		t.saveMessage(id, chatID, sent.MessageID)
+	}
+
 	log.Printf("Уведомление отправлено в Telegram: %s", alert.Rule.Name)
 	return nil
 }
 
-// formatMessage форматирует сообщение для Telegram
-func (t *TelegramNotifier) formatMessage(alert *models.Alert) string {
-	var sb strings.Builder
-
-	// Эмодзи в зависимости от уровня важности
-	var emoji string
-	switch alert.Level {
-	case models.AlertCritical:
-		emoji = "🔴"
-	case models.AlertHigh:
-		emoji = "🟠"
-	case models.AlertMedium:
-		emoji = "🟡"
-	case models.AlertLow:
-		emoji = "🔵"
-	default:
-		emoji = "🔔"
+// saveMessage запоминает message_id. Сообщение уже отправлено,
+// поэтому ошибка сохранения только логируется: повтор придёт новым сообщением
+func (t *TelegramNotifier) saveMessage(alertID models.ID, chatID int64, messageID int) {
+	t.mu.Lock()
+	store := t.messages
+	t.mu.Unlock()
+	if store == nil {
+		return
+	}
+
+	if err := store.SaveMessage(alertID, messageKey(chatID), messageID); err != nil {
+		log.Printf("⚠️ %v", err)
 	}
+}
 
-	sb.WriteString(fmt.Sprintf("%s <b>%s</b>\n", emoji, escapeHTML(alert.Rule.Name)))
-	sb.WriteString(fmt.Sprintf("<b>Тема:</b> %s\n", escapeHTML(alert.Email.Subject)))
-	sb.WriteString(fmt.Sprintf("<b>От:</b> %s\n", escapeHTML(alert.Email.From)))
+// message возвращает message_id сообщения, отправленного для алерта в чат
+func (t *TelegramNotifier) message(alertID models.ID, chatID int64) (int, bool) {
+	t.mu.Lock()
+	store := t.messages
+	t.mu.Unlock()
+	if store == nil {
+		return 0, false
+	}
+	return store.Message(alertID, messageKey(chatID))
+}
 
-	sb.WriteString(fmt.Sprintf("<b>Время:</b> %s\n", alert.Email.Date.Format("15:04 02.01")))
-	// sb.WriteString(fmt.Sprintf("<b>Причина:</b> %s\n", escapeHTML(alert.Reason)))
+func messageKey(chatID int64) string {
+	return fmt.Sprintf("telegram:%d", chatID)
+}
 
-	return sb.String()
+// telegramParseMode возвращает parse_mode Telegram для формата шаблона
+func telegramParseMode(format Format) string {
+	switch format {
+	case FormatHTML:
+		return tgbotapi.ModeHTML
+	case FormatMarkdownV2:
+		return "MarkdownV2"
+	default:
+		return ""
+	}
 }
 
 // testConnection проверяет подключение к Telegram
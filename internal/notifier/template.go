@@ -0,0 +1,211 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// Format - формат текста, который понимает получатель
+type Format string
+
+const (
+	FormatHTML       Format = "html"
+	FormatMarkdownV2 Format = "markdownv2"
+	FormatPlain      Format = "plain"
+	FormatMrkdwn     Format = "mrkdwn"   // Slack
+	FormatMarkdown   Format = "markdown" // Mattermost (CommonMark), Discord
+)
+
+// ParseFormat проверяет название формата, пустая строка даёт def
+func ParseFormat(name string, def Format) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case "":
+		return def, nil
+	case FormatHTML, FormatMarkdownV2, FormatPlain, FormatMrkdwn:
+		return f, nil
+	default:
+		return "", fmt.Errorf("неизвестный формат сообщения: %s", name)
+	}
+}
+
+// Template - шаблон сообщения (text/template), которому доступен весь Alert
+//
+// Пример: {{emoji .Level}} {{escape .Rule.Name}}: {{escape .Email.Subject}} {{link .}}
+type Template struct {
+	format Format
+	tmpl   *template.Template
+}
+
+// NewTemplate разбирает шаблон. Функция escape экранирует под формат шаблона
+func NewTemplate(name, text string, format Format) (*Template, error) {
+	funcs := template.FuncMap{
+		"escape":         escapeFunc(format),
+		"escapeHTML":     escapeHTML,
+		"escapeAttr":     escapeAttr,
+		"escapeMarkdown": escapeMarkdownV2,
+		"escapePlain":    escapePlain,
+		"emoji":          levelEmoji,
+		"color":          levelColor,
+		"link":           firstLink,
+		"snippet":        snippet,
+		"truncate":       func(max int, text string) string { return truncate(text, max) },
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора шаблона %s: %w", name, err)
+	}
+
+	return &Template{format: format, tmpl: tmpl}, nil
+}
+
+// Render подставляет алерт в шаблон
+func (t *Template) Render(alert *models.Alert) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, alert); err != nil {
+		return "", fmt.Errorf("ошибка выполнения шаблона %s: %w", t.tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// Renderer выбирает шаблон нотификатора: шаблон из правила, если он задан, иначе общий
+type Renderer struct {
+	name    string
+	format  Format
+	def     *Template
+	summary *Template
+
+	mu    sync.Mutex
+	rules map[string]*Template // текст шаблона правила -> разобранный шаблон
+}
+
+// NewRenderer создаёт рендерер. Если text пустой, используется defText
+func NewRenderer(name, text, defText string, format Format) (*Renderer, error) {
+	if text == "" {
+		text = defText
+	}
+
+	def, err := NewTemplate(name, text, format)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := NewTemplate(name+"/summary", summaryTemplate(format), format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Renderer{
+		name:    name,
+		format:  format,
+		def:     def,
+		summary: summary,
+		rules:   make(map[string]*Template),
+	}, nil
+}
+
+// Format возвращает формат, в котором рендерятся сообщения
+func (r *Renderer) Format() Format {
+	return r.format
+}
+
+// Render рендерит сообщение для алерта
+func (r *Renderer) Render(alert *models.Alert) (string, error) {
+	if alert.IsSummary() {
+		return r.summary.Render(alert)
+	}
+
+	tmpl, err := r.templateFor(alert.Rule)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(alert)
+}
+
+// templateFor возвращает шаблон правила для этого нотификатора или общий
+func (r *Renderer) templateFor(rule *models.Rule) (*Template, error) {
+	if rule == nil || rule.Templates[r.name] == "" {
+		return r.def, nil
+	}
+	text := rule.Templates[r.name]
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tmpl, ok := r.rules[text]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := NewTemplate(fmt.Sprintf("%s/%s", r.name, rule.Name), text, r.format)
+	if err != nil {
+		return nil, err
+	}
+	r.rules[text] = tmpl
+	return tmpl, nil
+}
+
+// escapeAttr экранирует значение HTML-атрибута, в том числе кавычки
+func escapeAttr(text string) string {
+	return html.EscapeString(text)
+}
+
+// escapeFunc возвращает функцию экранирования для формата
+func escapeFunc(format Format) func(string) string {
+	switch format {
+	case FormatHTML, FormatMrkdwn:
+		// Slack mrkdwn требует экранировать те же &, <, >
+		return escapeHTML
+	case FormatMarkdownV2:
+		return escapeMarkdownV2
+	case FormatMarkdown:
+		// Mattermost и Discord показывают HTML-сущности как есть, поэтому экранируем обратной косой чертой
+		return escapeCommonMark
+	default:
+		return escapePlain
+	}
+}
+
+// escapeMarkdownV2 экранирует спецсимволы Telegram MarkdownV2
+func escapeMarkdownV2(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune("_*[]()~`>#+-=|{}.!\\", r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// escapeCommonMark экранирует спецсимволы CommonMark (Markdown в Mattermost)
+func escapeCommonMark(text string) string {
+	var sb strings.Builder
+	for _, r := range escapePlain(text) {
+		if strings.ContainsRune("\\`*_{}[]()<>#+-.!|~", r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// escapePlain убирает управляющие символы, кроме переводов строки и табуляции
+func escapePlain(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\n' && r != '\t' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// snippet возвращает начало текста письма в одну строку
+func snippet(max int, text string) string {
+	return truncate(strings.Join(strings.Fields(text), " "), max)
+}
@@ -0,0 +1,94 @@
+package notifier
+
+// Встроенные шаблоны сообщений. Их можно переопределить полем template
+// в настройках нотификатора или templates в правиле.
+// Ссылки внутри атрибутов экранируются escapeAttr: escape не трогает кавычки
+
+const defaultHTMLTemplate = `{{emoji .Level}} <b>{{escape .Rule.Name}}</b>
+<b>Тема:</b> {{escape .Email.Subject}}
+<b>От:</b> {{escape .Email.From}}
+<b>Время:</b> {{.Email.Date.Format "15:04 02.01"}}
+{{with .Reason}}<b>Причина:</b> {{escape .}}
+{{end}}`
+
+const defaultMarkdownV2Template = `{{emoji .Level}} *{{escape .Rule.Name}}*
+*Тема:* {{escape .Email.Subject}}
+*От:* {{escape .Email.From}}
+*Время:* {{escape (.Email.Date.Format "15:04 02.01")}}
+{{with .Reason}}*Причина:* {{escape .}}
+{{end}}`
+
+const defaultPlainTemplate = `Тема: {{escape .Email.Subject}}
+От: {{escape .Email.From}}
+{{with .Reason}}Причина: {{escape .}}
+{{end}}{{with link .}}Ссылка: {{.}}
+{{end}}`
+
+const defaultMrkdwnTemplate = `*Тема:* {{escape .Email.Subject}}
+*От:* {{escape .Email.From}}
+*Время:* {{.Email.Date.Format "15:04 02.01"}}
+{{with .Reason}}*Причина:* {{escape .}}
+{{end}}{{with link .}}<{{.}}|Открыть ссылку>{{end}}`
+
+const defaultMattermostTemplate = `**Тема:** {{escape .Email.Subject}}
+**От:** {{escape .Email.From}}
+**Время:** {{.Email.Date.Format "15:04 02.01"}}
+{{with .Reason}}**Причина:** {{escape .}}
+{{end}}{{with link .}}[Открыть ссылку]({{escape .}}){{end}}`
+
+// Discord отклоняет embed с пустыми полями, поэтому у темы и отправителя есть заглушки
+const defaultDiscordTemplate = `**Тема:** {{with .Email.Subject}}{{escape .}}{{else}}(без темы){{end}}
+**От:** {{with .Email.From}}{{escape .}}{{else}}(отправитель неизвестен){{end}}{{with .Reason}}
+**Причина:** {{escape .}}{{end}}`
+
+const defaultMatrixTemplate = `{{emoji .Level}} <font color="{{color .Level}}"><b>{{escape .Rule.Name}}</b></font><br>
+<b>Тема:</b> {{escape .Email.Subject}}<br>
+<b>От:</b> {{escape .Email.From}}<br>
+{{with .Reason}}<b>Причина:</b> {{escape .}}<br>
+{{end}}{{with link .}}<a href="{{escapeAttr .}}">Открыть ссылку</a>{{end}}`
+
+// Шаблоны сводок (Alert.Group не пустой): пачка алертов одним сообщением
+
+const summaryHTMLTemplate = `{{emoji .Level}} <b>{{escape .Email.Subject}}</b>
+{{range .Group}}• {{escape .Email.Subject}} — {{escape .Email.From}} ({{.Score}}){{with link .}} <a href="{{escapeAttr .}}">ссылка</a>{{end}}
+{{end}}`
+
+const summaryMarkdownV2Template = `{{emoji .Level}} *{{escape .Email.Subject}}*
+{{range .Group}}• {{escape .Email.Subject}} — {{escape .Email.From}} \({{.Score}}\){{with link .}} {{escape .}}{{end}}
+{{end}}`
+
+const summaryPlainTemplate = `{{escape .Email.Subject}}
+{{range .Group}}• {{escape .Email.Subject}} — {{escape .Email.From}} ({{.Score}}){{with link .}} {{.}}{{end}}
+{{end}}`
+
+const summaryMatrixTemplate = `{{emoji .Level}} <b>{{escape .Email.Subject}}</b><br>
+{{range .Group}}• {{escape .Email.Subject}} — {{escape .Email.From}} ({{.Score}}){{with link .}} <a href="{{escapeAttr .}}">ссылка</a>{{end}}<br>
+{{end}}`
+
+// summaryTemplate возвращает встроенный шаблон сводки для формата
+func summaryTemplate(format Format) string {
+	switch format {
+	case FormatHTML:
+		return summaryHTMLTemplate
+	case FormatMarkdownV2:
+		return summaryMarkdownV2Template
+	default:
+		return summaryPlainTemplate
+	}
+}
+
+// defaultTemplate возвращает встроенный шаблон для формата
+func defaultTemplate(format Format) string {
+	switch format {
+	case FormatHTML:
+		return defaultHTMLTemplate
+	case FormatMarkdownV2:
+		return defaultMarkdownV2Template
+	case FormatMrkdwn:
+		return defaultMrkdwnTemplate
+	case FormatMarkdown:
+		return defaultMattermostTemplate
+	default:
+		return defaultPlainTemplate
+	}
+}
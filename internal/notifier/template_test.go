@@ -0,0 +1,130 @@
+package notifier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		text     string
+		expected string
+	}{
+		{
+			name:     "HTML",
+			format:   FormatHTML,
+			text:     "<b>A & B</b>",
+			expected: "&lt;b&gt;A &amp; B&lt;/b&gt;",
+		},
+		{
+			name:     "MarkdownV2",
+			format:   FormatMarkdownV2,
+			text:     "med-osmotr_2025 (запись).",
+			expected: "med\\-osmotr\\_2025 \\(запись\\)\\.",
+		},
+		{
+			name:     "Plain",
+			format:   FormatPlain,
+			text:     "строка\x00\x1b[1m\nвторая",
+			expected: "строка[1m\nвторая",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := escapeFunc(tt.format)(tt.text)
+			if result != tt.expected {
+				t.Errorf("incorrect result, expected: '%v', got: '%v'", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	renderer, err := NewRenderer("telegram", "", "{{.Rule.Name}}: {{escape .Email.Subject}}", FormatHTML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	alert := testAlert()
+	result, err := renderer.Render(alert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "Медосмотр: Запись &lt;на&gt; медосмотр" {
+		t.Errorf("incorrect default template result, got: '%v'", result)
+	}
+
+	// Шаблон из правила важнее шаблона нотификатора
+	alert.Rule.Templates = map[string]string{"telegram": "{{.Level}} {{.Score}} {{link .}}"}
+	result, err = renderer.Render(alert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "critical 90 https://hse.ru/med" {
+		t.Errorf("incorrect rule template result, got: '%v'", result)
+	}
+
+	// Шаблон другого нотификатора не применяется
+	alert.Rule.Templates = map[string]string{"slack": "slack"}
+	if result, _ := renderer.Render(alert); result == "slack" {
+		t.Error("template of another notifier must not be used")
+	}
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	alert := testAlert()
+	alert.Reason = "причина"
+	alert.Rule = &models.Rule{Name: "Правило"}
+
+	for _, format := range []Format{FormatHTML, FormatMarkdownV2, FormatPlain, FormatMrkdwn, FormatMarkdown} {
+		renderer, err := NewRenderer("test", "", defaultTemplate(format), format)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		result, err := renderer.Render(alert)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", format, err)
+		}
+		if !strings.Contains(result, "причина") {
+			t.Errorf("%s: reason is missing: %s", format, result)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	alert := testAlert()
+	alert.Reason = "Баллы: 90/50"
+
+	// Текст алерта собирается из того же шаблона, что и простые уведомления
+	expected := "🔴 Медосмотр\nТема: Запись <на> медосмотр\nОт: med@hse.ru\nПричина: Баллы: 90/50\nСсылка: https://hse.ru/med\n"
+	if result := Message(alert); result != expected {
+		t.Errorf("incorrect message, expected: '%v', got: '%v'", expected, result)
+	}
+}
+
+func TestMatrixLinkAttribute(t *testing.T) {
+	alert := testAlert()
+	alert.Email.Links = []string{`https://hse.ru/?a=1&b="><script>`}
+
+	renderer, err := NewRenderer("matrix", "", defaultMatrixTemplate, FormatHTML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := renderer.Render(alert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, `href="https://hse.ru/?a=1&amp;b=&#34;&gt;&lt;script&gt;"`) {
+		t.Errorf("link attribute is not escaped: %s", result)
+	}
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	if _, err := NewTemplate("bad", "{{.Rule.Name", FormatPlain); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,53 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// webhookTimeout - таймаут HTTP запросов к внешним сервисам
+const webhookTimeout = 10 * time.Second
+
+// newHTTPClient создает HTTP клиент для нотификаторов
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: webhookTimeout}
+}
+
+// sendJSON отправляет payload в формате JSON и проверяет код ответа
+func sendJSON(client *http.Client, method, url string, headers map[string]string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга: %w", err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// checkResponse возвращает ошибку, если сервис ответил не 2xx
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("сервис вернул %s: %s", resp.Status, bytes.TrimSpace(data))
+}
@@ -0,0 +1,260 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func testAlert() *models.Alert {
+	return &models.Alert{
+		ID:   "alert-1",
+		Rule: &models.Rule{Name: "Медосмотр"},
+		Email: &models.Email{
+			From:    "med@hse.ru",
+			Subject: "Запись <на> медосмотр",
+			Date:    time.Date(2025, 10, 1, 9, 30, 0, 0, time.UTC),
+			Links:   []string{"https://hse.ru/med"},
+		},
+		Score: 90,
+		Level: models.AlertCritical,
+	}
+}
+
+// captureServer поднимает фейковый сервер и сохраняет последний запрос
+func captureServer(t *testing.T, status int) (*httptest.Server, *http.Request, *map[string]any) {
+	t.Helper()
+	var req http.Request
+	body := make(map[string]any)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = *r
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid json body: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &req, &body
+}
+
+func TestSlackSend(t *testing.T) {
+	server, _, body := captureServer(t, http.StatusOK)
+
+	slack, err := NewSlack(&config.SlackConfig{Enabled: true, WebhookURL: server.URL, Channel: "#mail"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := slack.Send(testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if (*body)["channel"] != "#mail" {
+		t.Errorf("incorrect channel, got: '%v'", (*body)["channel"])
+	}
+	attachment := (*body)["attachments"].([]any)[0].(map[string]any)
+	if attachment["color"] != levelColor(models.AlertCritical) {
+		t.Errorf("incorrect color, got: '%v'", attachment["color"])
+	}
+	if attachment["title_link"] != "https://hse.ru/med" {
+		t.Errorf("incorrect link, got: '%v'", attachment["title_link"])
+	}
+	if !strings.Contains(attachment["text"].(string), "Запись &lt;на&gt; медосмотр") {
+		t.Errorf("subject is not escaped, got: '%v'", attachment["text"])
+	}
+}
+
+func TestDiscordSend(t *testing.T) {
+	server, _, body := captureServer(t, http.StatusNoContent)
+
+	alert := testAlert()
+	alert.Email.Subject = "*Срочно* запись_на `медосмотр`"
+	discord, _ := NewDiscord(&config.DiscordConfig{Enabled: true, WebhookURL: server.URL})
+	if err := discord.Send(alert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	embed := (*body)["embeds"].([]any)[0].(map[string]any)
+	if !strings.Contains(embed["description"].(string), "\\*Срочно\\* запись\\_на \\`медосмотр\\`") {
+		t.Errorf("subject is not escaped, got: '%v'", embed["description"])
+	}
+	if int(embed["color"].(float64)) != 0xd32f2f {
+		t.Errorf("incorrect color, got: '%v'", embed["color"])
+	}
+	if embed["url"] != "https://hse.ru/med" {
+		t.Errorf("incorrect url, got: '%v'", embed["url"])
+	}
+}
+
+func TestDiscordEmptyFields(t *testing.T) {
+	server, _, body := captureServer(t, http.StatusNoContent)
+
+	alert := testAlert()
+	alert.Email.Subject = ""
+	alert.Email.From = ""
+	discord, _ := NewDiscord(&config.DiscordConfig{Enabled: true, WebhookURL: server.URL})
+	if err := discord.Send(alert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	embed := (*body)["embeds"].([]any)[0].(map[string]any)
+	description := embed["description"].(string)
+	if !strings.Contains(description, "(без темы)") || !strings.Contains(description, "(отправитель неизвестен)") {
+		t.Errorf("expected placeholders for empty fields, got: '%v'", description)
+	}
+}
+
+func TestMattermostSend(t *testing.T) {
+	server, _, body := captureServer(t, http.StatusOK)
+
+	alert := testAlert()
+	alert.Email.Subject = "Q&A <медосмотр> *срочно*"
+	mattermost, _ := NewMattermost(&config.MattermostConfig{Enabled: true, WebhookURL: server.URL})
+	if err := mattermost.Send(alert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attachment := (*body)["attachments"].([]any)[0].(map[string]any)
+	text := attachment["text"].(string)
+	if !strings.Contains(text, `Q&A \<медосмотр\> \*срочно\*`) {
+		t.Errorf("subject must be escaped as Markdown, got: '%v'", text)
+	}
+	if strings.Contains(text, "&amp;") || strings.Contains(text, "&lt;") {
+		t.Errorf("Mattermost must not get HTML entities, got: '%v'", text)
+	}
+	if !strings.Contains(text, "[Открыть ссылку](https://hse\\.ru/med)") {
+		t.Errorf("incorrect link, got: '%v'", text)
+	}
+}
+
+func TestMatrixSend(t *testing.T) {
+	server, req, body := captureServer(t, http.StatusOK)
+
+	matrix, _ := NewMatrix(&config.MatrixConfig{
+		Enabled:       true,
+		HomeserverURL: server.URL + "/",
+		AccessToken:   "secret",
+		RoomID:        "!room:example.org",
+	})
+	if err := matrix.Send(testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.Method != http.MethodPut {
+		t.Errorf("incorrect method, got: '%v'", req.Method)
+	}
+	if !strings.HasSuffix(req.URL.Path, "/send/m.room.message/alert-1") {
+		t.Errorf("incorrect path, got: '%v'", req.URL.Path)
+	}
+	if req.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("incorrect auth header, got: '%v'", req.Header.Get("Authorization"))
+	}
+	if (*body)["format"] != "org.matrix.custom.html" {
+		t.Errorf("incorrect format, got: '%v'", (*body)["format"])
+	}
+}
+
+func TestWebhookErrorStatus(t *testing.T) {
+	server, _, _ := captureServer(t, http.StatusForbidden)
+
+	mattermost, _ := NewMattermost(&config.MattermostConfig{Enabled: true, WebhookURL: server.URL})
+	if err := mattermost.Send(testAlert()); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestDisabledNotifier(t *testing.T) {
+	slack, err := NewSlack(&config.SlackConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if slack.IsAvailable() {
+		t.Error("notifier without webhook_url must be unavailable")
+	}
+}
+
+func TestNtfySend(t *testing.T) {
+	server, req, body := captureServer(t, http.StatusOK)
+
+	ntfy, _ := NewNtfy(&config.NtfyConfig{Enabled: true, ServerURL: server.URL, Topic: "mail", Token: "tk_secret"})
+	if err := ntfy.Send(testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.Header.Get("Authorization") != "Bearer tk_secret" {
+		t.Errorf("incorrect auth header, got: '%v'", req.Header.Get("Authorization"))
+	}
+	if (*body)["topic"] != "mail" {
+		t.Errorf("incorrect topic, got: '%v'", (*body)["topic"])
+	}
+	if (*body)["priority"] != float64(5) {
+		t.Errorf("incorrect priority, got: '%v'", (*body)["priority"])
+	}
+	if (*body)["click"] != "https://hse.ru/med" {
+		t.Errorf("incorrect click action, got: '%v'", (*body)["click"])
+	}
+}
+
+func TestGotifySend(t *testing.T) {
+	server, req, body := captureServer(t, http.StatusOK)
+
+	gotify, _ := NewGotify(&config.GotifyConfig{Enabled: true, ServerURL: server.URL, Token: "app-token"})
+	alert := testAlert()
+	alert.Level = models.AlertMedium
+	if err := gotify.Send(alert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.URL.Path != "/message" {
+		t.Errorf("incorrect path, got: '%v'", req.URL.Path)
+	}
+	if req.Header.Get("X-Gotify-Key") != "app-token" {
+		t.Errorf("incorrect token header, got: '%v'", req.Header.Get("X-Gotify-Key"))
+	}
+	if (*body)["priority"] != float64(5) {
+		t.Errorf("incorrect priority, got: '%v'", (*body)["priority"])
+	}
+	click := (*body)["extras"].(map[string]any)["client::notification"].(map[string]any)["click"].(map[string]any)
+	if click["url"] != "https://hse.ru/med" {
+		t.Errorf("incorrect click url, got: '%v'", click["url"])
+	}
+}
+
+func TestSlackSendTo(t *testing.T) {
+	server, req, body := captureServer(t, http.StatusOK)
+
+	slack, _ := NewSlack(&config.SlackConfig{
+		Enabled:    true,
+		WebhookURL: server.URL + "/default",
+		Channel:    "#mail",
+		Targets: map[string]config.WebhookTarget{
+			"hr":       {Channel: "#hr"},
+			"students": {WebhookURL: server.URL + "/students"},
+		},
+	})
+
+	if err := slack.SendTo("hr", testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.URL.Path != "/default" || (*body)["channel"] != "#hr" {
+		t.Errorf("incorrect destination, got: '%v' '%v'", req.URL.Path, (*body)["channel"])
+	}
+
+	if err := slack.SendTo("students", testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.URL.Path != "/students" || (*body)["channel"] != "#mail" {
+		t.Errorf("incorrect destination, got: '%v' '%v'", req.URL.Path, (*body)["channel"])
+	}
+
+	if err := slack.SendTo("unknown", testAlert()); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,596 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// State - состояние записи в очереди
+type State string
+
+const (
+	StatePending   State = "pending"   // ждёт отправки или повтора
+	StateDelivered State = "delivered" // доставлено
+	StateDead      State = "dead"      // попытки исчерпаны
+)
+
+// Entry - одно уведомление в очереди: алерт и действие, через которое его отправить
+type Entry struct {
+	ID          models.ID     `json:"id"`
+	Action      models.Action `json:"action"`
+	Alert       *models.Alert `json:"alert"`
+	State       State         `json:"state"`
+	Attempts    int           `json:"attempts"`
+	NextAttempt time.Time     `json:"next_attempt"`
+	LastError   string        `json:"last_error,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	DeadAt      time.Time     `json:"dead_at,omitzero"`
+	// Group - группа отложенной записи (см. Deferral): подошедшие одновременно записи
+	// одного действия и группы уходят одним сообщением
+	Group string `json:"group,omitempty"`
+}
+
+// Deferral - ответ SendFunc «отправить позже» (тихие часы, лимит нотификатора).
+// Запись переносится на Until без траты попытки
+type Deferral struct {
+	Until time.Time
+	Group string
+}
+
+func (d *Deferral) Error() string {
+	return fmt.Sprintf("отправка отложена до %s", d.Until.Format("15:04:05"))
+}
+
+// Defer возвращает Deferral для SendFunc
+func Defer(until time.Time, group string) error {
+	return &Deferral{Until: until, Group: group}
+}
+
+// MergeFunc объединяет алерты записей одной группы в одно сообщение
+type MergeFunc func(group string, alerts []*models.Alert) *models.Alert
+
+// compactMinRecords - сколько лишних снимков записей терпим в файле до его переписывания
+const compactMinRecords = 1000
+
+// SendFunc отправляет алерт через действие
+type SendFunc func(action models.Action, alert *models.Alert) error
+
+// Outbox - персистентная очередь уведомлений.
+// Алерт сначала записывается на диск, потом доставляется с повторами
+// и экспоненциальной задержкой. После MaxAttempts неудач запись уходит в dead.
+// Отложенные отправкой записи (Deferral) ждут на диске вместе с остальными.
+//
+// Файл - журнал: каждая строка - JSON-снимок записи, последний снимок с тем же ID главный.
+// Изменения дописываются в конец одним fsync на пачку, файл переписывается целиком,
+// только когда устаревших снимков становится много
+type Outbox struct {
+	path           string
+	send           SendFunc
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	deadRetention  time.Duration
+	maxDead        int
+
+	mu      sync.Mutex
+	entries []*Entry
+	records int  // снимков в файле
+	dirty   bool // файл мог остаться с оборванной строкой, дописывать в него нельзя
+	wake    chan struct{}
+	now     func() time.Time
+
+	// OnResult вызывается после каждой попытки отправки, кроме отложенных
+	OnResult func(entry *Entry, err error)
+	// Merge объединяет отложенные записи группы. Без него записи отправляются по одной
+	Merge MergeFunc
+}
+
+// New создаёт очередь и загружает из файла то, что не успели отправить в прошлый раз
+func New(cfg *config.OutboxConfig, send SendFunc) (*Outbox, error) {
+	o := &Outbox{
+		path:           cfg.Path,
+		send:           send,
+		maxAttempts:    cfg.MaxAttempts,
+		initialBackoff: cfg.GetInitialBackoff(),
+		maxBackoff:     cfg.GetMaxBackoff(),
+		deadRetention:  cfg.GetDeadRetention(),
+		maxDead:        cfg.MaxDead,
+		wake:           make(chan struct{}, 1),
+		now:            time.Now,
+	}
+
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+
+	if pending := o.Pending(); pending > 0 {
+		log.Printf("В очереди уведомлений осталось с прошлого запуска: %d", pending)
+	}
+	return o, nil
+}
+
+// Enqueue записывает уведомление в очередь. Когда метод вернул nil, уведомление уже на диске
+func (o *Outbox) Enqueue(action models.Action, alert *models.Alert) error {
+	o.mu.Lock()
+	now := o.now()
+	entry := &Entry{
+		ID:          models.GenerateID(),
+		Action:      action,
+		Alert:       alert,
+		State:       StatePending,
+		NextAttempt: now,
+		CreatedAt:   now,
+	}
+	o.entries = append(o.entries, entry)
+	err := o.persistLocked(entry)
+	if err != nil {
+		// Вызывающий узнает об ошибке и повторит, запись в памяти дала бы дубль
+		o.entries = o.entries[:len(o.entries)-1]
+	}
+	o.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("ошибка записи в очередь уведомлений: %w", err)
+	}
+
+	o.notify()
+	return nil
+}
+
+// Run доставляет уведомления, пока не отменён ctx
+func (o *Outbox) Run(ctx context.Context) {
+	for {
+		o.deliverDue()
+
+		timer := time.NewTimer(o.untilNext())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-o.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Pending возвращает количество уведомлений, ожидающих отправки
+func (o *Outbox) Pending() int {
+	return o.count(StatePending)
+}
+
+// Deferred возвращает количество отложенных уведомлений (см. Deferral), ждущих отправки
+func (o *Outbox) Deferred() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var n int
+	for _, entry := range o.entries {
+		if entry.State == StatePending && entry.Group != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Dead возвращает копии уведомлений, которые не удалось доставить
+func (o *Outbox) Dead() []Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var dead []Entry
+	for _, entry := range o.entries {
+		if entry.State == StateDead {
+			dead = append(dead, *entry)
+		}
+	}
+	return dead
+}
+
+// RequeueDead возвращает недоставленные уведомления в очередь с новым счётчиком попыток.
+// Без ids - все недоставленные. Возвращает количество возвращённых
+func (o *Outbox) RequeueDead(ids ...models.ID) (int, error) {
+	o.mu.Lock()
+	now := o.now()
+	var requeued []*Entry
+	for _, entry := range o.entries {
+		if entry.State != StateDead || (len(ids) > 0 && !slices.Contains(ids, entry.ID)) {
+			continue
+		}
+		entry.State = StatePending
+		entry.Attempts = 0
+		entry.NextAttempt = now
+		entry.DeadAt = time.Time{}
+		requeued = append(requeued, entry)
+	}
+	err := o.persistLocked(requeued...)
+	o.mu.Unlock()
+
+	if err != nil {
+		return 0, fmt.Errorf("ошибка записи очереди уведомлений: %w", err)
+	}
+	if len(requeued) > 0 {
+		log.Printf("В очередь возвращено недоставленных уведомлений: %d", len(requeued))
+		o.notify()
+	}
+	return len(requeued), nil
+}
+
+// PurgeDead удаляет недоставленные уведомления. Без ids - все недоставленные.
+// Возвращает количество удалённых
+func (o *Outbox) PurgeDead(ids ...models.ID) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	kept := o.entries[:0]
+	var purged int
+	for _, entry := range o.entries {
+		if entry.State == StateDead && (len(ids) == 0 || slices.Contains(ids, entry.ID)) {
+			purged++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	clear(o.entries[len(kept):])
+	o.entries = kept
+
+	if purged == 0 {
+		return 0, nil
+	}
+	if err := o.rewriteLocked(); err != nil {
+		return 0, fmt.Errorf("ошибка записи очереди уведомлений: %w", err)
+	}
+	log.Printf("Удалено недоставленных уведомлений: %d", purged)
+	return purged, nil
+}
+
+// deliverDue пытается отправить все уведомления, время которых пришло
+func (o *Outbox) deliverDue() {
+	o.mu.Lock()
+	now := o.now()
+	var due []*Entry
+	for _, entry := range o.entries {
+		if entry.State == StatePending && !entry.NextAttempt.After(now) {
+			due = append(due, entry)
+		}
+	}
+	o.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	// Отправляем без блокировки, чтобы Enqueue не ждал сеть
+	for _, batch := range o.batches(due) {
+		alert := batch[0].Alert
+		if len(batch) > 1 {
+			alerts := make([]*models.Alert, len(batch))
+			for i, entry := range batch {
+				alerts[i] = entry.Alert
+			}
+			alert = o.Merge(batch[0].Group, alerts)
+		}
+		err := o.send(batch[0].Action, alert)
+
+		var deferral *Deferral
+		if errors.As(err, &deferral) {
+			o.mu.Lock()
+			for _, entry := range batch {
+				entry.NextAttempt = deferral.Until
+				if entry.Group == "" {
+					entry.Group = deferral.Group
+				}
+			}
+			o.mu.Unlock()
+			continue
+		}
+
+		for _, entry := range batch {
+			o.mu.Lock()
+			entry.Attempts++
+			switch {
+			case err == nil:
+				entry.State = StateDelivered
+				entry.LastError = ""
+			case entry.Attempts >= o.maxAttempts:
+				entry.State = StateDead
+				entry.DeadAt = o.now()
+				entry.LastError = err.Error()
+				log.Printf("Уведомление %s через %s не доставлено после %d попыток: %v",
+					entry.ID, entry.Action, entry.Attempts, err)
+			default:
+				entry.LastError = err.Error()
+				entry.NextAttempt = o.now().Add(o.backoff(entry.Attempts))
+				log.Printf("Ошибка отправки через %s (попытка %d), повтор в %s: %v",
+					entry.Action, entry.Attempts, entry.NextAttempt.Format("15:04:05"), err)
+			}
+			o.mu.Unlock()
+
+			if o.OnResult != nil {
+				o.OnResult(entry, err)
+			}
+		}
+	}
+
+	// Результаты всей пачки - одной записью на диск
+	o.mu.Lock()
+	o.compactLocked()
+	if err := o.persistLocked(due...); err != nil {
+		log.Printf("Ошибка сохранения очереди уведомлений: %v", err)
+	}
+	o.mu.Unlock()
+}
+
+// batches разбивает подошедшие записи на отправки: записи одного действия и группы
+// вместе (если задан Merge), остальные по одной. Порядок - по первой записи отправки
+func (o *Outbox) batches(due []*Entry) [][]*Entry {
+	type key struct {
+		action models.Action
+		group  string
+	}
+
+	var batches [][]*Entry
+	index := make(map[key]int)
+	for _, entry := range due {
+		if entry.Group == "" || o.Merge == nil {
+			batches = append(batches, []*Entry{entry})
+			continue
+		}
+		k := key{entry.Action, entry.Group}
+		if i, ok := index[k]; ok {
+			batches[i] = append(batches[i], entry)
+			continue
+		}
+		index[k] = len(batches)
+		batches = append(batches, []*Entry{entry})
+	}
+	return batches
+}
+
+// notify будит Run, не дожидаясь таймера
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// backoff возвращает задержку перед следующей попыткой: initial * 2^(attempts-1), не больше max
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.initialBackoff
+	for i := 1; i < attempts && delay < o.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, o.maxBackoff)
+}
+
+// untilNext возвращает время до ближайшей попытки
+func (o *Outbox) untilNext() time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	next := o.maxBackoff
+	now := o.now()
+	for _, entry := range o.entries {
+		if entry.State == StatePending {
+			next = min(next, entry.NextAttempt.Sub(now))
+		}
+	}
+	return max(next, 0)
+}
+
+func (o *Outbox) count(state State) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var n int
+	for _, entry := range o.entries {
+		if entry.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+// compactLocked убирает доставленные уведомления и dead старше срока хранения
+// или сверх лимита (сначала самые старые), остальные dead остаются для разбора
+func (o *Outbox) compactLocked() {
+	var dead int
+	for _, entry := range o.entries {
+		if entry.State == StateDead {
+			dead++
+		}
+	}
+
+	now := o.now()
+	kept := o.entries[:0]
+	var expired int
+	for _, entry := range o.entries {
+		switch entry.State {
+		case StateDelivered:
+			continue
+		case StateDead:
+			deadAt := entry.DeadAt
+			if deadAt.IsZero() {
+				deadAt = entry.CreatedAt
+			}
+			if (o.maxDead > 0 && dead > o.maxDead) || (o.deadRetention > 0 && now.Sub(deadAt) > o.deadRetention) {
+				dead--
+				expired++
+				continue
+			}
+		}
+		kept = append(kept, entry)
+	}
+	clear(o.entries[len(kept):])
+	o.entries = kept
+
+	if expired > 0 {
+		log.Printf("Удалено устаревших недоставленных уведомлений: %d", expired)
+	}
+}
+
+// persistLocked сохраняет изменённые записи: дописывает их снимки в журнал
+// или переписывает файл, если устаревших снимков накопилось слишком много
+func (o *Outbox) persistLocked(changed ...*Entry) error {
+	if len(changed) == 0 && !o.dirty {
+		return nil
+	}
+	if o.dirty || o.records+len(changed) > 2*len(o.entries)+compactMinRecords {
+		return o.rewriteLocked()
+	}
+	if err := o.appendLocked(changed); err != nil {
+		o.dirty = true
+		return err
+	}
+	return nil
+}
+
+// load читает очередь из файла: журнал снимков или JSON-массив прежнего формата
+func (o *Outbox) load() error {
+	data, err := os.ReadFile(o.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Первый запуск
+		}
+		return fmt.Errorf("ошибка чтения очереди уведомлений: %w", err)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &o.entries); err != nil {
+			return fmt.Errorf("ошибка демаршалинга очереди уведомлений: %w", err)
+		}
+		// Переводим файл в формат журнала
+		o.dirty = true
+	} else {
+		o.entries = o.replay(data)
+	}
+
+	o.compactLocked()
+	if o.dirty || o.records > 2*len(o.entries)+compactMinRecords {
+		if err := o.rewriteLocked(); err != nil {
+			return fmt.Errorf("ошибка записи очереди уведомлений: %w", err)
+		}
+	}
+	return nil
+}
+
+// replay собирает записи из журнала: для каждого ID последний снимок.
+// Оборванная при сбое последняя строка пропускается, файл тогда переписывается
+func (o *Outbox) replay(data []byte) []*Entry {
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		o.dirty = true
+	}
+
+	var entries []*Entry
+	index := make(map[models.ID]int)
+	for line := range bytes.Lines(data) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		o.records++
+
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			log.Printf("Пропущена повреждённая запись очереди уведомлений: %v", err)
+			o.dirty = true
+			continue
+		}
+		if i, ok := index[entry.ID]; ok {
+			entries[i] = &entry
+		} else {
+			index[entry.ID] = len(entries)
+			entries = append(entries, &entry)
+		}
+	}
+	return entries
+}
+
+// appendLocked дописывает снимки записей в журнал одним fsync
+func (o *Outbox) appendLocked(entries []*Entry) error {
+	var buf bytes.Buffer
+	if err := encodeEntries(&buf, entries); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.path), 0755); err != nil {
+		return fmt.Errorf("ошибка создания директории: %w", err)
+	}
+
+	f, err := os.OpenFile(o.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+	if err := writeSync(f, buf.Bytes()); err != nil {
+		return fmt.Errorf("ошибка записи файла: %w", err)
+	}
+
+	o.records += len(entries)
+	return nil
+}
+
+// rewriteLocked атомарно переписывает журнал: по снимку на каждую запись
+func (o *Outbox) rewriteLocked() error {
+	var buf bytes.Buffer
+	if err := encodeEntries(&buf, o.entries); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.path), 0755); err != nil {
+		return fmt.Errorf("ошибка создания директории: %w", err)
+	}
+
+	tmpFile := o.path + ".tmp"
+	f, err := os.OpenFile(tmpFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка создания временного файла: %w", err)
+	}
+	if err := writeSync(f, buf.Bytes()); err != nil {
+		return fmt.Errorf("ошибка записи временного файла: %w", err)
+	}
+
+	if err := os.Rename(tmpFile, o.path); err != nil {
+		return fmt.Errorf("ошибка переименовывания файла: %w", err)
+	}
+
+	o.records = len(o.entries)
+	o.dirty = false
+	return nil
+}
+
+// encodeEntries пишет записи по одной JSON-строке
+func encodeEntries(buf *bytes.Buffer, entries []*Entry) error {
+	encoder := json.NewEncoder(buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("ошибка маршалинга: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeSync записывает данные, дожидается сброса на диск и закрывает файл
+func writeSync(f *os.File, data []byte) error {
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
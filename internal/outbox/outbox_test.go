@@ -0,0 +1,312 @@
+package outbox
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func testConfig(t *testing.T) *config.OutboxConfig {
+	return &config.OutboxConfig{
+		Path:                  filepath.Join(t.TempDir(), "outbox.json"),
+		MaxAttempts:           3,
+		InitialBackoffSeconds: 10,
+		MaxBackoffSeconds:     15,
+	}
+}
+
+func testAlert() *models.Alert {
+	return &models.Alert{
+		ID:    "alert-1",
+		Rule:  &models.Rule{Name: "Медосмотр"},
+		Email: &models.Email{Subject: "Запись на медосмотр"},
+	}
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	cfg := testConfig(t)
+	var calls int
+	o, err := New(cfg, func(models.Action, *models.Alert) error {
+		calls++
+		return fmt.Errorf("telegram недоступен")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	o.now = func() time.Time { return now }
+
+	if err := o.Enqueue(models.Action{Type: models.ActionNotifyTelegram}, testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o.deliverDue()
+	if calls != 1 || o.Pending() != 1 {
+		t.Fatalf("expected 1 attempt and pending entry, got: %d attempts, %d pending", calls, o.Pending())
+	}
+
+	// До истечения задержки повтора не будет
+	now = now.Add(9 * time.Second)
+	o.deliverDue()
+	if calls != 1 {
+		t.Errorf("retry before backoff, got: %d attempts", calls)
+	}
+
+	// Вторая задержка 20s ограничена max_backoff_seconds
+	now = now.Add(time.Second)
+	o.deliverDue()
+	now = now.Add(15 * time.Second)
+	o.deliverDue()
+	if calls != 3 {
+		t.Errorf("incorrect attempts, expected: 3, got: %d", calls)
+	}
+	if o.Pending() != 0 || len(o.Dead()) != 1 {
+		t.Errorf("entry must be dead, got: %d pending, %d dead", o.Pending(), len(o.Dead()))
+	}
+}
+
+func TestPendingSurvivesRestart(t *testing.T) {
+	cfg := testConfig(t)
+	failing := func(models.Action, *models.Alert) error { return fmt.Errorf("сеть недоступна") }
+
+	o, err := New(cfg, failing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Enqueue(models.Action{Type: models.ActionNotifyTelegram, Target: "students"}, testAlert()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.deliverDue()
+
+	// Новый процесс: очередь читается из файла и доставляется
+	var delivered []models.Action
+	restarted, err := New(cfg, func(action models.Action, alert *models.Alert) error {
+		if alert.Email.Subject != "Запись на медосмотр" {
+			t.Errorf("alert was not restored, got subject: '%v'", alert.Email.Subject)
+		}
+		delivered = append(delivered, action)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restarted.Pending() != 1 {
+		t.Fatalf("expected 1 pending entry after restart, got: %d", restarted.Pending())
+	}
+
+	restarted.now = func() time.Time { return time.Now().Add(time.Hour) }
+	restarted.deliverDue()
+	if len(delivered) != 1 || delivered[0].Target != "students" {
+		t.Errorf("incorrect delivery, got: %v", delivered)
+	}
+	if restarted.Pending() != 0 {
+		t.Errorf("delivered entry must leave the queue, got: %d pending", restarted.Pending())
+	}
+}
+
+func TestJournal(t *testing.T) {
+	cfg := testConfig(t)
+	o, err := New(cfg, func(models.Action, *models.Alert) error { return nil })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for range 3 {
+		if err := o.Enqueue(models.Action{Type: models.ActionNotifyTelegram}, testAlert()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	o.deliverDue()
+
+	// Постановка и доставка дописываются в конец: 3 снимка pending и 3 delivered
+	data, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 6 {
+		t.Errorf("expected 6 records, got: %d", lines)
+	}
+
+	// Оборванная при сбое строка не мешает загрузке
+	if err := os.WriteFile(cfg.Path, append(data, `{"id":"torn","sta`...), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restarted, err := New(cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if restarted.Pending() != 0 {
+		t.Errorf("delivered entries must not be restored, got: %d pending", restarted.Pending())
+	}
+	if data, _ := os.ReadFile(cfg.Path); len(data) != 0 {
+		t.Errorf("journal must be compacted after torn record, got: %q", data)
+	}
+}
+
+func TestLegacyFormat(t *testing.T) {
+	cfg := testConfig(t)
+	legacy := `[{"id":"e1","action":{"type":"notify_telegram"},"alert":{"id":"a1"},"state":"pending","attempts":1}]`
+	if err := os.WriteFile(cfg.Path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	o, err := New(cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o.Pending() != 1 {
+		t.Fatalf("expected 1 pending entry, got: %d", o.Pending())
+	}
+	data, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte(`{"id":"e1"`)) {
+		t.Errorf("file must be converted to journal, got: %s", data)
+	}
+}
+
+func TestDeadRetention(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MaxAttempts = 1
+	cfg.DeadRetentionDays = 1
+	cfg.MaxDead = 2
+	o, err := New(cfg, func(models.Action, *models.Alert) error { return fmt.Errorf("нет сети") })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	o.now = func() time.Time { return now }
+
+	for range 3 {
+		if err := o.Enqueue(models.Action{Type: models.ActionNotifyTelegram}, testAlert()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	o.deliverDue()
+	if dead := len(o.Dead()); dead != 2 {
+		t.Errorf("expected max_dead 2 dead entries, got: %d", dead)
+	}
+
+	now = now.Add(25 * time.Hour)
+	o.deliverDue() // ничего не отправляет
+	o.mu.Lock()
+	o.compactLocked()
+	o.mu.Unlock()
+	if dead := len(o.Dead()); dead != 0 {
+		t.Errorf("expired dead entries must be removed, got: %d", dead)
+	}
+}
+
+func TestRequeueAndPurgeDead(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MaxAttempts = 1
+	fail := true
+	var sent int
+	o, err := New(cfg, func(models.Action, *models.Alert) error {
+		if fail {
+			return fmt.Errorf("нет сети")
+		}
+		sent++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for range 2 {
+		if err := o.Enqueue(models.Action{Type: models.ActionNotifyTelegram}, testAlert()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	o.deliverDue()
+	dead := o.Dead()
+	if len(dead) != 2 {
+		t.Fatalf("expected 2 dead entries, got: %d", len(dead))
+	}
+
+	// Одну отправляем заново, она доставляется с новым счётчиком попыток
+	fail = false
+	if n, err := o.RequeueDead(dead[0].ID); err != nil || n != 1 {
+		t.Fatalf("expected 1 requeued entry, got: %d, %v", n, err)
+	}
+	o.deliverDue()
+	if sent != 1 || o.Pending() != 0 {
+		t.Errorf("requeued entry must be delivered, got: %d sent, %d pending", sent, o.Pending())
+	}
+
+	// Оставшуюся удаляем, после перезапуска её нет
+	if n, err := o.PurgeDead(); err != nil || n != 1 {
+		t.Fatalf("expected 1 purged entry, got: %d, %v", n, err)
+	}
+	restarted, err := New(cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(restarted.Dead()) != 0 || restarted.Pending() != 0 {
+		t.Errorf("queue must be empty, got: %d dead, %d pending", len(restarted.Dead()), restarted.Pending())
+	}
+}
+
+func TestDeferralMerged(t *testing.T) {
+	cfg := testConfig(t)
+	now := time.Date(2025, 10, 1, 23, 0, 0, 0, time.UTC)
+	morning := now.Add(8 * time.Hour)
+
+	var sent []*models.Alert
+	o, err := New(cfg, func(action models.Action, alert *models.Alert) error {
+		if now.Before(morning) {
+			return Defer(morning, "quiet")
+		}
+		sent = append(sent, alert)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	o.now = func() time.Time { return now }
+	o.Merge = func(group string, alerts []*models.Alert) *models.Alert {
+		return models.NewSummaryAlert(fmt.Sprintf("%s: %d", group, len(alerts)), nil, alerts)
+	}
+
+	action := models.Action{Type: models.ActionNotifyTelegram}
+	for range 2 {
+		if err := o.Enqueue(action, testAlert()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	o.deliverDue()
+
+	// Отложенное не тратит попытки и переживает перезапуск
+	if o.Pending() != 2 || o.Deferred() != 2 || len(sent) != 0 {
+		t.Fatalf("expected 2 deferred entries, got: %d pending, %d deferred, %d sent", o.Pending(), o.Deferred(), len(sent))
+	}
+	for _, entry := range o.entries {
+		if entry.Attempts != 0 || !entry.NextAttempt.Equal(morning) {
+			t.Errorf("incorrect deferred entry: %+v", entry)
+		}
+	}
+	restarted, err := New(cfg, o.send)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restarted.now = o.now
+	restarted.Merge = o.Merge
+
+	// Утром записи группы уходят одним сообщением
+	now = morning
+	restarted.deliverDue()
+	if len(sent) != 1 || sent[0].Email.Subject != "quiet: 2" {
+		t.Fatalf("expected one merged alert, got: %d", len(sent))
+	}
+	if restarted.Pending() != 0 {
+		t.Errorf("merged entries must be delivered, got: %d pending", restarted.Pending())
+	}
+}
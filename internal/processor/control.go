@@ -0,0 +1,281 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/history"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/metrics"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/notifier"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/outbox"
+)
+
+const (
+	// maxRecentAlerts - сколько последних алертов помним без истории
+	maxRecentAlerts = 100
+	// maxRecentEmails - сколько последних писем помним для проверки правил
+	maxRecentEmails = 50
+)
+
+var (
+	// ErrRuleNotFound - правила с таким ID или именем нет
+	ErrRuleNotFound = errors.New("правило не найдено")
+	// ErrInvalidRule - правило не прошло проверку конфига
+	ErrInvalidRule = errors.New("правило не прошло проверку")
+	// ErrEmailNotFound - письма нет среди последних
+	ErrEmailNotFound = errors.New("письмо не найдено среди последних")
+)
+
+// Ready сообщает, что обработчик запущен и подключён к почте
+func (p *Processor) Ready() bool {
+	p.mu.Lock()
+	started := p.started
+	p.mu.Unlock()
+	return started && p.watcher.IsConnected()
+}
+
+// Pause приостанавливает плановые проверки почты
+func (p *Processor) Pause() {
+	p.watcher.Pause()
+	log.Println("Проверка почты приостановлена")
+}
+
+// Resume возобновляет плановые проверки почты
+func (p *Processor) Resume() {
+	p.watcher.Resume()
+	log.Println("Проверка почты возобновлена")
+}
+
+// Paused сообщает, приостановлены ли проверки почты
+func (p *Processor) Paused() bool {
+	return p.watcher.Paused()
+}
+
+// CheckNow запускает внеочередную проверку почты
+func (p *Processor) CheckNow() {
+	p.watcher.CheckNow()
+}
+
+// Rules возвращает копии загруженных правил
+func (p *Processor) Rules() []models.Rule {
+	p.rulesMu.RLock()
+	defer p.rulesMu.RUnlock()
+
+	rules := make([]models.Rule, 0, len(p.config.Rules))
+	for _, rule := range p.config.Rules {
+		rules = append(rules, *rule)
+	}
+	return rules
+}
+
+// SetRuleEnabled включает или выключает правило по ID или имени до перезапуска или перезагрузки конфига
+func (p *Processor) SetRuleEnabled(idOrName string, enabled bool) (models.Rule, error) {
+	p.rulesMu.Lock()
+	defer p.rulesMu.Unlock()
+
+	index := p.findRuleLocked(idOrName)
+	if index < 0 {
+		return models.Rule{}, fmt.Errorf("%w: %s", ErrRuleNotFound, idOrName)
+	}
+
+	rule := *p.config.Rules[index]
+	rule.Enabled = enabled
+	p.replaceRuleLocked(index, &rule)
+	log.Printf("Правило %s: enabled=%v", rule.Name, enabled)
+	return rule, nil
+}
+
+// UpdateRule заменяет правило с ID или именем idOrName на rule: проверяет его вместе
+// со всем конфигом, сохраняет в файл, где правило описано, и сразу применяет. ID правила не меняется
+func (p *Processor) UpdateRule(idOrName string, rule models.Rule) (models.Rule, error) {
+	p.rulesMu.Lock()
+	defer p.rulesMu.Unlock()
+
+	index := p.findRuleLocked(idOrName)
+	if index < 0 {
+		return models.Rule{}, fmt.Errorf("%w: %s", ErrRuleNotFound, idOrName)
+	}
+	rule.ID = p.config.Rules[index].ID
+
+	// Проверяем конфиг целиком, как при загрузке: уникальность имён, получатели действий и т.д.
+	candidate := *p.config
+	candidate.Rules = slices.Clone(p.config.Rules)
+	candidate.Rules[index] = &rule
+	if err := config.Validate(&candidate); err != nil {
+		return models.Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	// Шаблоны проверяем до сохранения: иначе каждый алерт правила упадёт при отправке
+	if err := notifier.ValidateTemplates([]*models.Rule{&rule}); err != nil {
+		return models.Rule{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+
+	if p.config.Path != "" {
+		source := p.config.RuleSource(index)
+		if err := config.SaveRule(source.File, source.Index, &rule); err != nil {
+			return models.Rule{}, fmt.Errorf("ошибка сохранения правила: %w", err)
+		}
+	}
+
+	p.replaceRuleLocked(index, &rule)
+	log.Printf("Правило %s обновлено", rule.Name)
+	return rule, nil
+}
+
+// ExplainRule разбирает письмо из последних правилом rule (например, несохранённой правкой)
+func (p *Processor) ExplainRule(rule models.Rule, emailID models.ID) (filter.RuleTrace, error) {
+	email, ok := p.RecentEmail(emailID)
+	if !ok {
+		return filter.RuleTrace{}, fmt.Errorf("%w: %s", ErrEmailNotFound, emailID)
+	}
+
+	p.rulesMu.RLock()
+	engine := filter.NewConfiguredEngine(p.config, []*models.Rule{&rule})
+	p.rulesMu.RUnlock()
+	return engine.Explain(email)[0], nil
+}
+
+// RecentEmails возвращает последние письма, от новых к старым
+func (p *Processor) RecentEmails() []*models.Email {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	emails := slices.Clone(p.emails)
+	slices.Reverse(emails)
+	return emails
+}
+
+// RecentEmail ищет письмо среди последних по ID
+func (p *Processor) RecentEmail(id models.ID) (*models.Email, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, email := range p.emails {
+		if email.ID == id {
+			return email, true
+		}
+	}
+	return nil, false
+}
+
+// rememberEmail добавляет письмо в список последних, вытесняя старые
+func (p *Processor) rememberEmail(email *models.Email) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.emails = append(p.emails, email)
+	if len(p.emails) > maxRecentEmails {
+		p.emails = p.emails[len(p.emails)-maxRecentEmails:]
+	}
+}
+
+// replaceRuleLocked подменяет правило и пересоздаёт движок. Старое правило не меняется:
+// на него ещё могут ссылаться алерты в очереди, дайджесте и журнале повторов
+func (p *Processor) replaceRuleLocked(index int, rule *models.Rule) {
+	rules := slices.Clone(p.config.Rules)
+	rules[index] = rule
+	p.config.Rules = rules
+	p.filter = newLiveEngine(p.config, rules)
+}
+
+// findRuleLocked ищет правило по ID или имени, -1 если не найдено
+func (p *Processor) findRuleLocked(idOrName string) int {
+	return slices.IndexFunc(p.config.Rules, func(rule *models.Rule) bool {
+		return string(rule.ID) == idOrName || rule.Name == idOrName
+	})
+}
+
+// RecentAlerts возвращает последние алерты: из истории, если она включена,
+// иначе из памяти (не больше maxRecentAlerts, только с этого запуска)
+func (p *Processor) RecentAlerts(q history.Query) ([]history.AlertRecord, error) {
+	if p.history != nil {
+		return p.history.Alerts(q)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	alerts := make([]history.AlertRecord, 0)
+	for i := len(p.recent) - 1; i >= 0; i-- {
+		record := p.recent[i]
+		if q.Rule != "" && record.Rule != q.Rule {
+			continue
+		}
+		if !q.Since.IsZero() && record.CreatedAt.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && record.CreatedAt.After(q.Until) {
+			continue
+		}
+
+		alerts = append(alerts, record)
+		if q.Limit > 0 && len(alerts) >= q.Limit {
+			break
+		}
+	}
+	return alerts, nil
+}
+
+// DeadNotifications возвращает уведомления, которые не удалось доставить
+func (p *Processor) DeadNotifications() []outbox.Entry {
+	if p.outbox == nil {
+		return []outbox.Entry{}
+	}
+	return p.outbox.Dead()
+}
+
+// RequeueDead возвращает недоставленные уведомления в очередь, без ids - все
+func (p *Processor) RequeueDead(ids ...models.ID) (int, error) {
+	if p.outbox == nil {
+		return 0, nil
+	}
+	return p.outbox.RequeueDead(ids...)
+}
+
+// PurgeDead удаляет недоставленные уведомления, без ids - все
+func (p *Processor) PurgeDead(ids ...models.ID) (int, error) {
+	if p.outbox == nil {
+		return 0, nil
+	}
+	return p.outbox.PurgeDead(ids...)
+}
+
+// rememberAlert добавляет алерт в список последних, вытесняя старые
+func (p *Processor) rememberAlert(record history.AlertRecord) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.recent = append(p.recent, record)
+	if len(p.recent) > maxRecentAlerts {
+		p.recent = p.recent[len(p.recent)-maxRecentAlerts:]
+	}
+}
+
+// countRules обновляет счётчики правил
+func (p *Processor) countRules(results, nearMisses []*models.Alert) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, alert := range results {
+		stats := p.ruleStatsLocked(alert.Rule.Name)
+		stats.Alerts++
+		stats.LastAlert = alert.CreatedAt
+		metrics.Alerts.Inc(alert.Level.String())
+	}
+	for _, alert := range nearMisses {
+		p.ruleStatsLocked(alert.Rule.Name).NearMisses++
+	}
+}
+
+func (p *Processor) ruleStatsLocked(name string) *RuleStats {
+	stats, ok := p.stats.Rules[name]
+	if !ok {
+		stats = &RuleStats{}
+		p.stats.Rules[name] = stats
+	}
+	return stats
+}
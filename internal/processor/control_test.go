@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+)
+
+func TestUpdateRuleTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(reloadConfig), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := NewProcessor(cfg, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Сломанный шаблон не сохраняется и не применяется
+	rule := p.Rules()[0]
+	rule.Templates = map[string]string{"telegram": "{{.Rule.Name"}
+	if _, err := p.UpdateRule("rule-1", rule); !errors.Is(err, ErrInvalidRule) {
+		t.Fatalf("expected ErrInvalidRule, got: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != reloadConfig {
+		t.Errorf("config must stay unchanged, got:\n%s", data)
+	}
+	if rules := p.Rules(); len(rules[0].Templates) != 0 {
+		t.Errorf("broken template must not be applied, got: %+v", rules[0].Templates)
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/dedup"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/digest"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/history"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/mailwatcher"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
 	"github.com/Strochik12/CatchAnImportantLetter/internal/notifier"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/outbox"
 )
 
 // Processor главный координатор системы
@@ -19,7 +25,24 @@ type Processor struct {
 	watcher  *mailwatcher.Watcher
 	filter   *filter.Engine
 	notifier *notifier.Manager
-	stats    *Stats
+	outbox   *outbox.Outbox
+	digest   *digest.Digest // nil, если дайджест выключен
+	dedup    *dedup.Store   // nil, если подавление повторов выключено
+	history  *history.Store // nil, если история выключена
+	shadow   *filter.Engine // теневые правила, nil если не заданы
+	report   *report
+	opts     Options
+
+	mu      sync.Mutex // защищает stats и recent: уведомления доставляются в отдельной горутине
+	stats   *Stats
+	recent  []history.AlertRecord // последние алерты, если история выключена
+	emails  []*models.Email       // последние письма для проверки правил из веб-интерфейса
+	started bool
+
+	// rulesMu защищает config.Rules, config.Scoring, config.Notifiers, filter и notifier:
+	// правила меняются через HTTP API и при перезагрузке конфига во время обработки
+	rulesMu  sync.RWMutex
+	reloadMu sync.Mutex // перезагрузки конфига идут по одной
 }
 
 type Stats struct {
@@ -27,27 +50,105 @@ type Stats struct {
 	AlertsGenerated   int
 	NotificationsSent int
 	LastActivity      time.Time
-	Errors            []error
+	Errors            []error // последние maxErrors ошибок
+	ErrorsTotal       int
+	Rules             map[string]*RuleStats // по имени правила
+}
+
+// RuleStats - счётчики одного правила
+type RuleStats struct {
+	Alerts     int       `json:"alerts"`
+	NearMisses int       `json:"near_misses"`
+	LastAlert  time.Time `json:"last_alert,omitzero"`
+}
+
+// maxErrors - сколько последних ошибок хранится в Stats.Errors
+const maxErrors = 100
+
+// Options - режимы работы обработчика
+type Options struct {
+	// DryRun - ничего не отправлять и не сохранять: ящик открывается только для чтения,
+	// алерты пишутся в лог и в ReportPath
+	DryRun bool
+	// ReportPath - файл JSON Lines для алертов dry-run и расхождений shadow-правил
+	ReportPath string
+	// ShadowRules - файл с правилами-кандидатами, которые проверяются рядом с боевыми
+	ShadowRules string
 }
 
 // NewProcessor создаёт новый обработчик
-func NewProcessor(cfg *config.Config) (*Processor, error) {
+func NewProcessor(cfg *config.Config, opts Options) (*Processor, error) {
 	watcher := mailwatcher.NewWatcher(cfg)
+	watcher.SetReadOnly(opts.DryRun)
+
+	engine := newLiveEngine(cfg, cfg.Rules)
+
+	report, err := newReport(opts.ReportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Processor{
+		config:  cfg,
+		watcher: watcher,
+		filter:  engine,
+		report:  report,
+		opts:    opts,
+		stats:   &Stats{LastActivity: time.Now(), Rules: make(map[string]*RuleStats)},
+	}
 
-	filter := filter.NewEngine(cfg.Rules)
+	if opts.ShadowRules != "" {
+		rules, err := config.LoadRules(opts.ShadowRules, &cfg.Scoring)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки теневых правил: %w", err)
+		}
+		p.shadow = filter.NewConfiguredEngine(cfg, rules)
+		log.Printf("Теневых правил загружено: %d", len(rules))
+	}
+
+	// В dry-run нет ни нотификаторов, ни очередей: ничего не отправляется и не сохраняется
+	if opts.DryRun {
+		return p, nil
+	}
 
-	notifier, err := notifier.NewManager(cfg)
+	p.notifier, err = notifier.NewManager(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания менеджера нотификаторов: %w", err)
 	}
 
-	return &Processor{
-		config:   cfg,
-		watcher:  watcher,
-		filter:   filter,
-		notifier: notifier,
-		stats:    &Stats{LastActivity: time.Now()},
-	}, nil
+	// Уведомления идут через персистентную очередь, чтобы не терять их при сбоях
+	p.outbox, err = outbox.New(&cfg.Outbox, p.send)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания очереди уведомлений: %w", err)
+	}
+	p.outbox.OnResult = p.onDelivery
+	p.outbox.Merge = notifier.MergeDeferred
+
+	// Малозначимые алерты копятся в дайджесте, который тоже уходит через очередь
+	if cfg.Digest.Enabled {
+		p.digest, err = digest.New(&cfg.Digest, p.outbox.Enqueue)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания дайджеста: %w", err)
+		}
+	}
+
+	if cfg.Dedup.Enabled {
+		p.dedup, err = dedup.New(&cfg.Dedup)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка создания журнала повторов: %w", err)
+		}
+		// Идентификаторы отправленных сообщений хранятся вместе с записями о повторах
+		p.notifier.SetMessageStore(p.dedup)
+	}
+
+	if cfg.History.Enabled {
+		p.history, err = history.Open(&cfg.History)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
 }
 
 // Start запускает мониторинг почты
@@ -55,12 +156,42 @@ func (p *Processor) Start(ctx context.Context) error {
 	log.Println("Запускаем HSE Email Alert System...")
 	log.Printf("Сервер: %s", p.config.IMAP.Server)
 	log.Printf("Пользователь: %s", p.config.IMAP.Username)
-	log.Printf("Правил загружено: %d", len(p.config.Rules))
-	log.Printf("Доступные нотификаторы: %v", p.notifier.GetAvailableNotifiers())
+	log.Printf("Правил загружено: %d", len(p.Rules()))
+	defer p.report.Close()
+
+	if p.opts.DryRun {
+		log.Println("Режим dry-run: уведомления не отправляются, состояние ящика не сохраняется")
+	} else {
+		log.Printf("Доступные нотификаторы: %v", p.currentNotifier().GetAvailableNotifiers())
+		defer func() {
+			if err := p.currentNotifier().Close(); err != nil {
+				log.Printf("Ошибка закрытия нотификаторов: %v", err)
+			}
+		}()
+
+		// Доставляем уведомления, в том числе оставшиеся с прошлого запуска
+		go p.outbox.Run(ctx)
+		if p.digest != nil {
+			go p.digest.Run(ctx)
+		}
+		if p.history != nil {
+			defer p.history.Close()
+			go p.history.Run(ctx)
+		}
+	}
 
 	// Запускаем мониторинг почты
 	emailCh, errorCh := p.watcher.Watch(ctx)
 
+	p.mu.Lock()
+	p.started = true
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.started = false
+		p.mu.Unlock()
+	}()
+
 	// Основной цикл обработки
 	for {
 		select {
@@ -69,15 +200,23 @@ func (p *Processor) Start(ctx context.Context) error {
 				return nil
 			}
 
+			p.mu.Lock()
 			p.stats.LastActivity = time.Now()
 			p.stats.EmailsProcessed += 1
+			p.mu.Unlock()
 
 			log.Printf("Новое письмо: %q", email.Subject)
+			p.rememberEmail(email)
 
 			// Обрабатываем письмо
+			// Письмо считается обработанным, только когда все уведомления по нему в очереди:
+			// иначе оно будет забрано снова при следующей проверке
 			if err := p.processEmail(email); err != nil {
 				log.Printf("Ошибка обработки письма: %v", err)
-				p.stats.Errors = append(p.stats.Errors, err)
+				p.addError(err)
+			} else if err := p.watcher.Ack(email.UID); err != nil {
+				log.Printf("Ошибка сохранения состояния почты: %v", err)
+				p.addError(err)
 			}
 
 		case err, ok := <-errorCh:
@@ -85,7 +224,7 @@ func (p *Processor) Start(ctx context.Context) error {
 				return nil
 			}
 			log.Printf("Ошибка мониторинга: %v", err)
-			p.stats.Errors = append(p.stats.Errors, err)
+			p.addError(err)
 
 		case <-ctx.Done():
 			log.Println("Останавливаем систему...")
@@ -99,7 +238,47 @@ func (p *Processor) processEmail(email *models.Email) error {
 	startTime := time.Now()
 
 	// 1. Фильтруем через движок правил
-	results := p.filter.Process(email)
+	p.rulesMu.RLock()
+	if p.config.Monitoring.Explain {
+		for _, trace := range p.filter.Explain(email) {
+			log.Print(trace.String())
+		}
+	}
+	results, nearMisses := p.filter.Evaluate(email)
+	p.rulesMu.RUnlock()
+	for _, alert := range slices.Concat(results, nearMisses) {
+		alert.Message = notifier.Message(alert)
+	}
+	p.countRules(results, nearMisses)
+
+	if p.shadow != nil {
+		shadowResults, _ := p.shadow.Evaluate(email)
+		for _, diff := range shadowDiff(email, results, shadowResults) {
+			p.report.write(diff)
+		}
+	}
+
+	if p.opts.DryRun {
+		p.recordDryRun(results, nearMisses)
+		return nil
+	}
+
+	if p.history != nil {
+		if err := p.history.RecordEmail(email, results); err != nil {
+			log.Printf("	%v", err)
+			p.addError(err)
+		}
+	}
+
+	// Почти сработавшие правила и алерты низкого уровня уходят в дайджест
+	if p.digest != nil {
+		results = p.collectDigest(results, nearMisses)
+	}
+
+	// Напоминания и повторные рассылки не должны приходить заново
+	if p.dedup != nil {
+		results = p.filterRepeats(results)
+	}
 
 	if len(results) == 0 {
 		log.Printf("	Письмо не подошло ни под одно правило")
@@ -107,10 +286,14 @@ func (p *Processor) processEmail(email *models.Email) error {
 	}
 
 	log.Printf("	Сработавших правил: %d", len(results))
+	p.mu.Lock()
 	p.stats.AlertsGenerated += len(results)
+	p.mu.Unlock()
 
-	// 2. Группируем действия по типу
-	actionsByType := make(map[models.ActionType]bool)
+	// 2. Группируем действия: каждому действию (нотификатор + получатель) - первый алерт,
+	// правило которого его запросило
+	alertByAction := make(map[models.Action]*models.Alert)
+	var actions []models.Action
 
 	for _, result := range results {
 		if result == nil {
@@ -122,66 +305,228 @@ func (p *Processor) processEmail(email *models.Email) error {
 			log.Printf("⚠️ Получен alert с nil Rule")
 			continue
 		}
+		p.recordAlert(result, history.OutcomeQueued)
 
 		for _, action := range result.Rule.Actions {
-			actionsByType[action] = true
+			if _, exists := alertByAction[action]; !exists {
+				alertByAction[action] = result
+				actions = append(actions, action)
+			}
 		}
 	}
 
-	// 3. Выполняем действия
-	var sentCount int
+	// 3. Ставим уведомления в очередь, доставкой и повторами занимается outbox
+	var queuedCount int
 	var errors []error
 
-	for actionType, _ := range actionsByType {
-		if !p.notifier.HasNotifier(actionType) {
-			log.Printf("	Нотификатор для %s, недоступен", actionType)
+	manager := p.currentNotifier()
+	for _, action := range actions {
+		if !manager.HasNotifier(action.Type) {
+			log.Printf("	Нотификатор для %s, недоступен", action.Type)
 			continue
 		}
 
-		// Создаем алерт для отправки (берем первый из результатов)
-		alert := results[0]
-
-		if alert == nil {
-			log.Printf("⚠️ Невозможно отправить alert, так как первый полученный alert - nil")
-			break
-		}
-
-		// Отправляем уведомление
-		if err := p.notifier.Send(actionType, alert); err != nil {
-			log.Printf("	Ошибка отправки %s: %v", actionType, err)
+		if err := p.outbox.Enqueue(action, alertByAction[action]); err != nil {
+			log.Printf("	Ошибка постановки в очередь %s: %v", action, err)
 			errors = append(errors, err)
 		} else {
-			sentCount++
-			p.stats.NotificationsSent++
+			queuedCount++
 		}
 	}
 
 	processingTime := time.Since(startTime)
-	log.Printf("	Результат: %d уведомлений отправлено за %v", sentCount, processingTime)
+	log.Printf("	Результат: %d уведомлений поставлено в очередь за %v", queuedCount, processingTime)
 
 	if len(errors) > 0 {
-		return fmt.Errorf("ошибки при отправке уведомлений: %v", errors)
+		return fmt.Errorf("ошибки при постановке уведомлений в очередь: %v", errors)
 	}
 
 	return nil
 }
 
-// GetStats возвращает статистику работы
+// newLiveEngine создаёт движок для писем из ящика, он один пишет метрики правил
+func newLiveEngine(cfg *config.Config, rules []*models.Rule) *filter.Engine {
+	engine := filter.NewConfiguredEngine(cfg, rules)
+	engine.EnableMetrics()
+	return engine
+}
+
+// recordDryRun записывает в отчёт то, что было бы отправлено
+func (p *Processor) recordDryRun(results, nearMisses []*models.Alert) {
+	p.mu.Lock()
+	p.stats.AlertsGenerated += len(results)
+	p.mu.Unlock()
+
+	for _, alert := range results {
+		kind := "alert"
+		if p.config.Digest.Enabled && alert.Level <= p.config.Digest.GetMaxLevel() {
+			kind = "digest"
+		}
+		p.report.write(alertEntry(kind, alert))
+	}
+	for _, alert := range nearMisses {
+		p.report.write(alertEntry("digest", alert))
+	}
+}
+
+// collectDigest добавляет в дайджест малозначимые алерты и возвращает остальные
+func (p *Processor) collectDigest(results, nearMisses []*models.Alert) []*models.Alert {
+	var urgent []*models.Alert
+	for _, alert := range append(results, nearMisses...) {
+		if !p.digest.Accepts(alert) {
+			urgent = append(urgent, alert)
+			continue
+		}
+
+		if err := p.digest.Add(alert); err != nil {
+			log.Printf("	%v", err)
+			p.addError(err)
+			continue
+		}
+		log.Printf("	В дайджест: %s (%d/%d)", alert.Rule.Name, alert.Score, alert.Rule.MinScore)
+		p.recordAlert(alert, history.OutcomeDigest)
+	}
+	return urgent
+}
+
+// filterRepeats убирает повторы ранее отправленных алертов.
+// В режиме update повтор остаётся и помечается, чтобы обновить прежнее сообщение
+func (p *Processor) filterRepeats(results []*models.Alert) []*models.Alert {
+	var fresh []*models.Alert
+	for _, alert := range results {
+		entry, err := p.dedup.Check(alert)
+		if err != nil {
+			// Лучше прислать повтор, чем потерять алерт
+			log.Printf("	%v", err)
+			p.addError(err)
+		}
+		if entry == nil {
+			fresh = append(fresh, alert)
+			continue
+		}
+
+		log.Printf("	Повтор алерта %s по правилу %s (повторов: %d)", entry.AlertID, entry.Rule, entry.Repeats)
+		if p.config.Dedup.Mode == config.DedupUpdate {
+			alert.ReplacesID = entry.AlertID
+			alert.Repeats = entry.Repeats
+			fresh = append(fresh, alert)
+		} else {
+			p.recordAlert(alert, history.OutcomeSuppressed)
+		}
+	}
+	return fresh
+}
+
+// onDelivery учитывает результат попытки доставки из очереди
+func (p *Processor) onDelivery(entry *outbox.Entry, err error) {
+	if p.history != nil {
+		record := history.DeliveryRecord{
+			EntryID: entry.ID,
+			AlertID: entry.Alert.ID,
+			Action:  entry.Action.String(),
+			Attempt: entry.Attempts,
+			State:   string(entry.State),
+		}
+		if entry.Alert.Rule != nil {
+			record.Rule = entry.Alert.Rule.Name
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if herr := p.history.RecordDelivery(record); herr != nil {
+			log.Printf("%v", herr)
+		}
+	}
+
+	if err != nil {
+		if entry.State == outbox.StateDead {
+			p.addError(fmt.Errorf("уведомление через %s не доставлено: %w", entry.Action, err))
+		}
+		return
+	}
+
+	p.mu.Lock()
+	p.stats.NotificationsSent++
+	p.mu.Unlock()
+
+	// Повторы считаются только к доставленным алертам
+	if p.dedup != nil {
+		if derr := p.dedup.Delivered(entry.Alert.ID); derr != nil {
+			log.Printf("%v", derr)
+			p.addError(derr)
+		}
+	}
+}
+
+// recordAlert сохраняет алерт в историю, а если она выключена - в список последних алертов
+func (p *Processor) recordAlert(alert *models.Alert, outcome history.Outcome) {
+	if p.history == nil {
+		p.rememberAlert(history.NewAlertRecord(alert, outcome))
+		return
+	}
+	if err := p.history.RecordAlert(alert, outcome); err != nil {
+		log.Printf("	%v", err)
+		p.addError(err)
+	}
+}
+
+// addError сохраняет ошибку в статистике, старые ошибки вытесняются
+func (p *Processor) addError(err error) {
+	p.mu.Lock()
+	p.stats.ErrorsTotal++
+	p.stats.Errors = append(p.stats.Errors, err)
+	if len(p.stats.Errors) > maxErrors {
+		p.stats.Errors = p.stats.Errors[len(p.stats.Errors)-maxErrors:]
+	}
+	p.mu.Unlock()
+}
+
+// GetStats возвращает копию статистики работы
 func (p *Processor) GetStats() *Stats {
-	return p.stats
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := *p.stats
+	stats.Errors = slices.Clone(p.stats.Errors)
+	stats.Rules = make(map[string]*RuleStats, len(p.stats.Rules))
+	for name, rule := range p.stats.Rules {
+		copied := *rule
+		stats.Rules[name] = &copied
+	}
+	return &stats
 }
 
 // PrintStats выводит статистику в консоль
 func (p *Processor) PrintStats() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	stats := p.stats
 	fmt.Println("\nСтатистика работы:")
 	fmt.Printf("	Обработано писем: %d\n", stats.EmailsProcessed)
 	fmt.Printf("	Сгенерировано алертов: %d\n", stats.AlertsGenerated)
 	fmt.Printf("	Отправлено уведомлений: %d\n", stats.NotificationsSent)
+	if !p.opts.DryRun {
+		fmt.Printf("	В очереди на отправку: %d\n", p.outbox.Pending())
+		if p.digest != nil {
+			fmt.Printf("	Ждут дайджеста: %d\n", p.digest.Len())
+		}
+		if deferred := p.outbox.Deferred(); deferred > 0 {
+			fmt.Printf("	Отложено (тихие часы, лимиты): %d\n", deferred)
+		}
+		if dead := len(p.outbox.Dead()); dead > 0 {
+			fmt.Printf("	Не доставлено (dead): %d\n", dead)
+		}
+		if p.history != nil {
+			if counts, err := p.history.Counts(); err == nil {
+				fmt.Printf("	В истории: писем %d, алертов %d, доставок %d\n", counts.Emails, counts.Alerts, counts.Deliveries)
+			}
+		}
+	}
 	fmt.Printf("	Последняя активность: %v\n", stats.LastActivity.Format("15:04:05"))
 
 	if len(stats.Errors) > 0 {
-		fmt.Printf("	Ошибок: %d\n", len(stats.Errors))
+		fmt.Printf("	Ошибок: %d (последние %d)\n", stats.ErrorsTotal, len(stats.Errors))
 		for i, err := range stats.Errors {
 			fmt.Printf("		%d. %v\n", i+1, err)
 		}
@@ -0,0 +1,23 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAddErrorKeepsLast(t *testing.T) {
+	p := &Processor{stats: &Stats{}}
+	for i := range maxErrors + 5 {
+		p.addError(fmt.Errorf("ошибка %d", i))
+	}
+
+	if p.stats.ErrorsTotal != maxErrors+5 {
+		t.Errorf("expected %d errors total, got: %d", maxErrors+5, p.stats.ErrorsTotal)
+	}
+	if len(p.stats.Errors) != maxErrors {
+		t.Fatalf("expected %d kept errors, got: %d", maxErrors, len(p.stats.Errors))
+	}
+	if got := p.stats.Errors[0].Error(); got != "ошибка 5" {
+		t.Errorf("expected oldest kept error 'ошибка 5', got: '%s'", got)
+	}
+}
@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/notifier"
+)
+
+// Reload перечитывает файл конфига и применяет его без перезапуска.
+// Если новый конфиг не загрузился или не прошёл проверку, остаётся прежний,
+// а ошибка уходит через нотификаторы
+func (p *Processor) Reload() error {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	if p.config.Path == "" {
+		return fmt.Errorf("конфиг загружен не из файла, перезагружать нечего")
+	}
+
+	next, err := config.Load(p.config.Path)
+	if err == nil {
+		err = p.apply(next)
+	}
+	if err != nil {
+		p.reportReloadError(err)
+		return err
+	}
+	return nil
+}
+
+// apply подменяет правила (вместе с подключёнными файлами), подсчёт баллов и нотификаторы.
+// Движок правил и менеджер нотификаторов меняются вместе под rulesMu: письмо обрабатывается
+// либо старым конфигом, либо новым целиком. Остальные блоки применяются только после перезапуска
+func (p *Processor) apply(next *config.Config) error {
+	// Менеджер создаётся заранее: при ошибке в нотификаторах остаётся прежний конфиг
+	var manager *notifier.Manager
+	if !p.opts.DryRun {
+		p.rulesMu.RLock()
+		notifiersChanged := !reflect.DeepEqual(p.config.Notifiers, next.Notifiers)
+		current := p.notifier
+		p.rulesMu.RUnlock()
+
+		if notifiersChanged {
+			var err error
+			manager, err = current.Reload(next)
+			if err != nil {
+				return fmt.Errorf("ошибка создания менеджера нотификаторов: %w", err)
+			}
+			if p.dedup != nil {
+				manager.SetMessageStore(p.dedup)
+			}
+		} else if err := notifier.ValidateTemplates(next.Rules); err != nil {
+			return err
+		}
+	}
+
+	p.rulesMu.Lock()
+	defer p.rulesMu.Unlock()
+
+	if sections := restartRequired(p.config, next); len(sections) > 0 {
+		log.Printf("Изменения в блоках %v применятся после перезапуска", sections)
+	}
+
+	p.config.Rules = next.Rules
+	p.config.IncludedFiles = next.IncludedFiles
+	p.config.RuleSources = next.RuleSources
+	p.config.Scoring = next.Scoring
+	p.config.Notifiers = next.Notifiers
+	p.filter = newLiveEngine(p.config, p.config.Rules)
+
+	if manager != nil {
+		// Отложенные лимитом и тихими часами алерты ждут в очереди и уйдут через новый менеджер.
+		// Старым менеджером под rulesMu уже никто не пользуется
+		previous := p.notifier
+		p.notifier = manager
+		if err := previous.CloseUnused(manager); err != nil {
+			log.Printf("Ошибка закрытия прежних нотификаторов: %v", err)
+		}
+	}
+
+	log.Printf("Конфигурация перезагружена. Правил: %d", len(p.config.Rules))
+	return nil
+}
+
+// restartRequired возвращает блоки конфига, которые изменились, но без перезапуска не применяются
+func restartRequired(current, next *config.Config) []string {
+	sections := []struct {
+		name          string
+		current, next any
+	}{
+		{"imap", current.IMAP, next.IMAP},
+		{"monitoring", current.Monitoring, next.Monitoring},
+		{"outbox", current.Outbox, next.Outbox},
+		{"digest", current.Digest, next.Digest},
+		{"dedup", current.Dedup, next.Dedup},
+		{"history", current.History, next.History},
+		{"api", current.API, next.API},
+	}
+
+	var changed []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.current, section.next) {
+			changed = append(changed, section.name)
+		}
+	}
+	return changed
+}
+
+// reportReloadError пишет ошибку перезагрузки в лог и статистику и отправляет её
+// во все действия текущих правил, чтобы её заметили без чтения логов
+func (p *Processor) reportReloadError(err error) {
+	log.Printf("Ошибка перезагрузки конфигурации, остаётся прежняя: %v", err)
+	p.addError(fmt.Errorf("перезагрузка конфигурации: %w", err))
+
+	if p.opts.DryRun {
+		return
+	}
+
+	alert := models.NewSystemAlert("Конфигурация не перезагружена", err.Error())
+	for _, action := range p.systemActions() {
+		if qerr := p.outbox.Enqueue(action, alert); qerr != nil {
+			log.Printf("	Ошибка постановки в очередь %s: %v", action, qerr)
+		}
+	}
+}
+
+// systemActions возвращает действия всех правил без повторов, для которых есть нотификатор
+func (p *Processor) systemActions() []models.Action {
+	p.rulesMu.RLock()
+	defer p.rulesMu.RUnlock()
+
+	var actions []models.Action
+	for _, rule := range p.config.Rules {
+		for _, action := range rule.Actions {
+			if p.notifier.HasNotifier(action.Type) && !slices.Contains(actions, action) {
+				actions = append(actions, action)
+			}
+		}
+	}
+	return actions
+}
+
+// send отправляет уведомление текущим менеджером нотификаторов. Вызывается из outbox.
+// Блокировка держится до конца отправки, чтобы перезагрузка не остановила менеджер посреди неё
+func (p *Processor) send(action models.Action, alert *models.Alert) error {
+	p.rulesMu.RLock()
+	defer p.rulesMu.RUnlock()
+	return p.notifier.Send(action, alert)
+}
+
+// currentNotifier возвращает текущий менеджер нотификаторов
+func (p *Processor) currentNotifier() *notifier.Manager {
+	p.rulesMu.RLock()
+	defer p.rulesMu.RUnlock()
+	return p.notifier
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/config"
+)
+
+const reloadConfig = `imap:
+  server: imap.example.com
+  username: user
+  password: secret
+  port: 993
+
+rules:
+  - id: "rule-1"
+    name: "Медосмотр"
+    enabled: true
+    min_score: 50
+    conditions:
+      - type: subject
+        operator: contains
+        value: "медосмотр"
+        weight: 60
+    actions: ["telegram"]
+`
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(reloadConfig), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := NewProcessor(cfg, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Новый вес и порог применяются без перезапуска
+	updated := strings.Replace(reloadConfig, "min_score: 50", "min_score: 40", 1)
+	if err := os.WriteFile(path, []byte(updated), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.Reload(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rules := p.Rules(); len(rules) != 1 || rules[0].MinScore != 40 {
+		t.Errorf("expected reloaded rule, got: %+v", rules)
+	}
+
+	// Невалидный конфиг не применяется, прежние правила остаются
+	invalid := strings.Replace(updated, "min_score: 40", "min_score: -1", 1)
+	if err := os.WriteFile(path, []byte(invalid), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.Reload(); err == nil {
+		t.Fatal("expected error for invalid config")
+	}
+	if rules := p.Rules(); rules[0].MinScore != 40 {
+		t.Errorf("expected previous rule to stay, got: %+v", rules[0])
+	}
+	if stats := p.GetStats(); stats.ErrorsTotal != 1 {
+		t.Errorf("expected reload error in stats, got: %d", stats.ErrorsTotal)
+	}
+}
@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+// ReportEntry - строка отчёта dry-run и shadow-режима (JSON Lines)
+type ReportEntry struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"` // alert, digest, shadow
+	Subject   string    `json:"subject"`
+	From      string    `json:"from"`
+	MessageID string    `json:"message_id,omitempty"`
+	Rule      string    `json:"rule,omitempty"`
+	Score     int       `json:"score,omitempty"`
+	Level     string    `json:"level,omitempty"`
+	Actions   []string  `json:"actions,omitempty"`
+	Diff      string    `json:"diff,omitempty"`
+}
+
+// report пишет в лог и, если задан файл, в JSON Lines то, что было бы отправлено
+type report struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newReport(path string) (*report, error) {
+	if path == "" {
+		return &report{}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания директории: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла отчёта: %w", err)
+	}
+	return &report{file: file}, nil
+}
+
+func (r *report) write(entry ReportEntry) {
+	switch entry.Kind {
+	case "shadow":
+		log.Printf("	[shadow] %s: %s", entry.Rule, entry.Diff)
+	default:
+		log.Printf("	[dry-run] %s: %s (%d, %s) -> %v", entry.Kind, entry.Rule, entry.Score, entry.Level, entry.Actions)
+	}
+
+	if r.file == nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Ошибка маршалинга отчёта: %v", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Write(append(data, '\n')); err != nil {
+		log.Printf("Ошибка записи отчёта: %v", err)
+	}
+}
+
+func (r *report) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// alertEntry - строка отчёта для алерта
+func alertEntry(kind string, alert *models.Alert) ReportEntry {
+	entry := ReportEntry{
+		Time:      time.Now(),
+		Kind:      kind,
+		Subject:   alert.Email.Subject,
+		From:      alert.Email.From,
+		MessageID: alert.Email.MessageID,
+		Rule:      alert.Rule.Name,
+		Score:     alert.Score,
+		Level:     alert.Level.String(),
+	}
+	for _, action := range alert.Rule.Actions {
+		entry.Actions = append(entry.Actions, action.String())
+	}
+	return entry
+}
+
+// shadowDiff сравнивает срабатывания боевых и теневых правил по имени правила
+func shadowDiff(email *models.Email, live, shadow []*models.Alert) []ReportEntry {
+	liveByRule := make(map[string]*models.Alert)
+	for _, alert := range live {
+		liveByRule[alert.Rule.Name] = alert
+	}
+	shadowByRule := make(map[string]*models.Alert)
+	for _, alert := range shadow {
+		shadowByRule[alert.Rule.Name] = alert
+	}
+
+	names := make([]string, 0, len(liveByRule)+len(shadowByRule))
+	for name := range liveByRule {
+		names = append(names, name)
+	}
+	for name := range shadowByRule {
+		if _, ok := liveByRule[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []ReportEntry
+	for _, name := range names {
+		l, s := liveByRule[name], shadowByRule[name]
+
+		var diff string
+		switch {
+		case s == nil:
+			diff = fmt.Sprintf("сработало только в боевых (%d, %s)", l.Score, l.Level)
+		case l == nil:
+			diff = fmt.Sprintf("сработало только в теневых (%d, %s)", s.Score, s.Level)
+		case l.Level != s.Level || l.Score != s.Score:
+			diff = fmt.Sprintf("боевые %d, %s; теневые %d, %s", l.Score, l.Level, s.Score, s.Level)
+		default:
+			continue
+		}
+
+		diffs = append(diffs, ReportEntry{
+			Time:      time.Now(),
+			Kind:      "shadow",
+			Subject:   email.Subject,
+			From:      email.From,
+			MessageID: email.MessageID,
+			Rule:      name,
+			Diff:      diff,
+		})
+	}
+	return diffs
+}
@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+func TestShadowDiff(t *testing.T) {
+	email := &models.Email{Subject: "Запись на медосмотр"}
+	alert := func(rule string, score int, level models.AlertLevel) *models.Alert {
+		return &models.Alert{Email: email, Rule: &models.Rule{Name: rule}, Score: score, Level: level}
+	}
+
+	live := []*models.Alert{
+		alert("Медосмотр", 80, models.AlertHigh),
+		alert("Рассылки", 60, models.AlertMedium),
+		alert("Дедлайны", 70, models.AlertMedium),
+	}
+	shadow := []*models.Alert{
+		alert("Медосмотр", 80, models.AlertHigh),
+		alert("Дедлайны", 95, models.AlertCritical),
+		alert("Новое правило", 65, models.AlertMedium),
+	}
+
+	diffs := shadowDiff(email, live, shadow)
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 diffs, got: %d (%+v)", len(diffs), diffs)
+	}
+
+	expected := []string{"Дедлайны", "Новое правило", "Рассылки"}
+	for i, diff := range diffs {
+		if diff.Rule != expected[i] || diff.Kind != "shadow" || diff.Diff == "" {
+			t.Errorf("incorrect diff %d, expected rule: '%v', got: %+v", i, expected[i], diff)
+		}
+	}
+}
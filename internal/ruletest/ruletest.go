@@ -0,0 +1,206 @@
+// Package ruletest прогоняет регрессионные тесты правил: письма-фикстуры
+// и ожидаемые срабатывания описываются в YAML рядом с конфигом.
+//
+//	cases:
+//	  - name: "Запись на медосмотр"
+//	    email:
+//	      subject: "Запись на медосмотр"
+//	      from: "med@hse.ru"
+//	    expect:
+//	      - rule: "Медосмотр для сотрудников"
+//	        level: high
+//	  - name: "Рассылка"
+//	    eml: "testdata/newsletter.eml" # путь относительно файла с тестами
+//	    expect: []                     # ни одно правило не должно сработать
+package ruletest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/mailwatcher"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+	"go.yaml.in/yaml/v3"
+)
+
+// Suite - набор тестов правил из одного файла
+type Suite struct {
+	Path  string `yaml:"-"`
+	Cases []Case `yaml:"cases"`
+}
+
+// Case - письмо и ожидаемые срабатывания правил
+type Case struct {
+	Name   string        `yaml:"name"`
+	Email  *EmailFixture `yaml:"email,omitempty"`
+	EML    string        `yaml:"eml,omitempty"`
+	Expect []Expectation `yaml:"expect"`
+}
+
+// EmailFixture - письмо, описанное прямо в YAML
+type EmailFixture struct {
+	Subject string            `yaml:"subject"`
+	From    string            `yaml:"from"`
+	To      []string          `yaml:"to,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Links   []string          `yaml:"links,omitempty"`
+}
+
+// Expectation - правило, которое должно сработать. Level пустой - уровень не проверяется.
+// NearMiss - правило должно почти сработать (попасть в дайджест)
+type Expectation struct {
+	Rule     string            `yaml:"rule"`
+	Level    models.AlertLevel `yaml:"level,omitempty"`
+	NearMiss bool              `yaml:"near_miss,omitempty"`
+}
+
+// Result - итог одного теста
+type Result struct {
+	Case  string
+	Diffs []string // расхождения с ожиданием
+	Err   error    // тест не удалось выполнить
+}
+
+// Passed проверяет, что тест прошёл
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+// Load читает набор тестов из файла
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения тестов: %w", err)
+	}
+
+	suite := &Suite{Path: path}
+	if err := yaml.Unmarshal(data, suite); err != nil {
+		return nil, fmt.Errorf("ошибка разбора %s: %w", path, err)
+	}
+
+	for i, c := range suite.Cases {
+		if c.Name == "" {
+			return nil, fmt.Errorf("%s: тест %d без имени", path, i+1)
+		}
+		if (c.Email == nil) == (c.EML == "") {
+			return nil, fmt.Errorf("%s: тест %q: нужно указать ровно одно из email и eml", path, c.Name)
+		}
+	}
+	return suite, nil
+}
+
+// Run прогоняет все тесты набора через движок правил
+func (s *Suite) Run(engine *filter.Engine) []Result {
+	results := make([]Result, 0, len(s.Cases))
+	for _, c := range s.Cases {
+		result := Result{Case: c.Name}
+
+		email, err := s.email(c)
+		if err != nil {
+			result.Err = err
+		} else {
+			alerts, nearMisses := engine.Evaluate(email)
+			result.Diffs = compare(c.Expect, append(alerts, nearMisses...))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// email возвращает письмо теста: из YAML или из .eml файла
+func (s *Suite) email(c Case) (*models.Email, error) {
+	if c.Email == nil {
+		path := c.EML
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(s.Path), path)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения письма: %w", err)
+		}
+		defer f.Close()
+		return mailwatcher.ParseEML(f)
+	}
+
+	email := models.NewEmail()
+	email.Subject = c.Email.Subject
+	email.From = c.Email.From
+	email.Body = c.Email.Body
+	if c.Email.To != nil {
+		email.To = c.Email.To
+	}
+	if c.Email.Headers != nil {
+		email.Headers = c.Email.Headers
+	}
+	if c.Email.Links != nil {
+		email.Links = c.Email.Links
+	}
+	return email, nil
+}
+
+// compare сравнивает ожидаемые срабатывания с фактическими
+func compare(expect []Expectation, alerts []*models.Alert) []string {
+	actual := make(map[string]*models.Alert)
+	for _, alert := range alerts {
+		actual[alert.Rule.Name] = alert
+	}
+
+	var diffs []string
+	expected := make(map[string]bool)
+	for _, exp := range expect {
+		expected[exp.Rule] = true
+
+		alert, ok := actual[exp.Rule]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("- %s: ожидалось %s, не сработало", exp.Rule, describe(exp.Level, exp.NearMiss)))
+		case alert.NearMiss != exp.NearMiss || exp.Level != 0 && alert.Level != exp.Level:
+			diffs = append(diffs, fmt.Sprintf("~ %s: ожидалось %s, получено %s", exp.Rule,
+				describe(exp.Level, exp.NearMiss), describeAlert(alert)))
+		}
+	}
+
+	var extra []string
+	for name, alert := range actual {
+		if !expected[name] {
+			extra = append(extra, fmt.Sprintf("+ %s: неожиданно %s", name, describeAlert(alert)))
+		}
+	}
+	sort.Strings(extra)
+	return append(diffs, extra...)
+}
+
+func describe(level models.AlertLevel, nearMiss bool) string {
+	switch {
+	case nearMiss:
+		return "почти сработало"
+	case level != 0:
+		return fmt.Sprintf("срабатывание %s", level)
+	default:
+		return "срабатывание"
+	}
+}
+
+func describeAlert(alert *models.Alert) string {
+	text := describe(alert.Level, alert.NearMiss)
+	if alert.NearMiss {
+		return fmt.Sprintf("%s (%d/%d)", text, alert.Score, alert.Rule.MinScore)
+	}
+	return fmt.Sprintf("%s (%d/%d, %s)", text, alert.Score, alert.Rule.MinScore, alert.LevelReason)
+}
+
+// Summary возвращает строку вида "3 из 4 тестов прошли"
+func Summary(results []Result) string {
+	var passed int
+	for _, r := range results {
+		if r.Passed() {
+			passed++
+		}
+	}
+	return fmt.Sprintf("%d из %d тестов прошли", passed, len(results))
+}
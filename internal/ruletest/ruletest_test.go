@@ -0,0 +1,83 @@
+package ruletest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Strochik12/CatchAnImportantLetter/internal/filter"
+	"github.com/Strochik12/CatchAnImportantLetter/internal/models"
+)
+
+const testSuite = `
+cases:
+  - name: "Запись на медосмотр"
+    email:
+      subject: "Запись на медосмотр"
+      from: "med@hse.ru"
+    expect:
+      - rule: "Медосмотр"
+        level: critical
+  - name: "Письмо из файла"
+    eml: "letter.eml"
+    expect:
+      - rule: "Медосмотр"
+        level: low
+  - name: "Рассылка"
+    email:
+      subject: "Новости: медосмотр перенесён"
+      from: "news@hse.ru"
+    expect: []
+`
+
+func testEngine() *filter.Engine {
+	return filter.NewEngine([]*models.Rule{{
+		Name:     "Медосмотр",
+		Enabled:  true,
+		MinScore: 30,
+		Conditions: []models.Condition{
+			{Type: models.ConditionSubject, Operator: models.OperatorContains, Value: "медосмотр", Weight: 50},
+			{Type: models.ConditionFrom, Operator: models.OperatorContains, Value: "hse.ru", Weight: 10},
+		},
+	}})
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules_test.yaml")
+	if err := os.WriteFile(path, []byte(testSuite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eml := "From: med@hse.ru\r\nSubject: Медосмотр\r\n\r\nТекст\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "letter.eml"), []byte(eml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	suite, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results := suite.Run(testEngine())
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got: %d", len(results))
+	}
+
+	if !results[0].Passed() {
+		t.Errorf("first case must pass, got: %v %v", results[0].Diffs, results[0].Err)
+	}
+
+	// Из файла правило срабатывает как critical, а ожидался low
+	if results[1].Passed() || len(results[1].Diffs) != 1 {
+		t.Errorf("second case must fail with level diff, got: %v %v", results[1].Diffs, results[1].Err)
+	}
+
+	// Сработало лишнее правило
+	if results[2].Passed() || len(results[2].Diffs) != 1 || results[2].Diffs[0][0] != '+' {
+		t.Errorf("third case must report unexpected match, got: %v", results[2].Diffs)
+	}
+
+	if summary := Summary(results); summary != "1 из 3 тестов прошли" {
+		t.Errorf("incorrect summary, got: '%v'", summary)
+	}
+}